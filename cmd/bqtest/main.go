@@ -2,14 +2,69 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/JoseTorrado/bqtest/pkg/config"
+	"github.com/JoseTorrado/bqtest/pkg/fileutil"
+	"github.com/JoseTorrado/bqtest/pkg/models"
 	"github.com/JoseTorrado/bqtest/pkg/runner"
 	"github.com/urfave/cli/v2"
 )
 
+// filterFlags select the tests a command operates on
+var filterFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "run",
+		Usage: "Only include tests whose name matches the regular expression",
+	},
+	&cli.StringSliceFlag{
+		Name:  "tag",
+		Usage: "Only include tests with any of the given tags",
+	},
+	&cli.StringSliceFlag{
+		Name:  "exclude-tag",
+		Usage: "Exclude tests with any of the given tags",
+	},
+}
+
+// newTestFilter builds the test filter from the filter flags
+func newTestFilter(c *cli.Context) (*config.TestFilter, error) {
+	filter := &config.TestFilter{
+		Tags:        c.StringSlice("tag"),
+		ExcludeTags: c.StringSlice("exclude-tag"),
+	}
+	if pattern := c.String("run"); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --run pattern: %v", err)
+		}
+		filter.Run = re
+	}
+	return filter, nil
+}
+
+// varFlag sets query variables from the command line
+var varFlag = &cli.StringSliceFlag{
+	Name:  "var",
+	Usage: "Set a query variable, overriding the configuration, e.g. --var start_date=2024-01-01",
+}
+
+// setVars applies the --var flags to every test of the configuration
+func setVars(c *cli.Context, testConfig *config.TestConfig) error {
+	vars, err := config.ParseVars(c.StringSlice("var"))
+	if err != nil {
+		return err
+	}
+	testConfig.SetVars(vars)
+	return nil
+}
+
 func main() {
 	app := &cli.App{
 		Name:  "bqtest",
@@ -19,7 +74,10 @@ func main() {
 				Name:    "run",
 				Aliases: []string{"r"},
 				Usage:   "Run BigQuery tests",
-				Flags: []cli.Flag{
+				Description: "Exits with status 1 when a test fails its comparison, 2 when the\n" +
+					"configuration is invalid or the emulator cannot be set up, and 3 when a test\n" +
+					"errors, e.g. on a SQL error, an unreadable input or a missing expected output.",
+				Flags: append([]cli.Flag{
 					&cli.StringFlag{
 						Name:     "config",
 						Aliases:  []string{"c"},
@@ -31,95 +89,334 @@ func main() {
 						Aliases: []string{"v"},
 						Usage:   "Enable verbose output",
 					},
-				},
+					&cli.StringSliceFlag{
+						Name:  "report",
+						Usage: "Write a report, e.g. junit=report.xml",
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Output format: text or json",
+						Value: "text",
+					},
+					&cli.IntFlag{
+						Name:    "parallel",
+						Aliases: []string{"p"},
+						Usage:   "Number of tests to run concurrently",
+						Value:   1,
+					},
+					varFlag,
+				}, filterFlags...),
 				Action: runTests,
 			},
+			{
+				Name:    "snapshot",
+				Aliases: []string{"s"},
+				Usage:   "Rewrite expected outputs with the actual query results",
+				Description: "Exits with status 2 when the configuration is invalid or the emulator\n" +
+					"cannot be set up, and 3 when a test errors or its expected output cannot be written.",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "config",
+						Aliases:  []string{"c"},
+						Usage:    "Path to the test configuration file",
+						Required: true,
+					},
+					varFlag,
+				},
+				Action: snapshotTests,
+			},
 			{
 				Name:    "list",
 				Aliases: []string{"l"},
 				Usage:   "List available tests",
-				Flags: []cli.Flag{
+				Flags: append([]cli.Flag{
 					&cli.StringFlag{
 						Name:     "config",
 						Aliases:  []string{"c"},
 						Usage:    "Path to the test configuration file",
 						Required: true,
 					},
-				},
+				}, filterFlags...),
 				Action: listTests,
 			},
+			{
+				Name:  "schema",
+				Usage: "Work with input table schemas",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "infer",
+						Usage:     "Print the JSON schema inferred from a CSV file",
+						ArgsUsage: "<csv>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "null-marker",
+								Usage: "Text that stands for NULL in the CSV file",
+							},
+						},
+						Action: inferSchema,
+					},
+				},
+			},
 		},
 	}
 
 	err := app.Run(os.Args)
 	if err != nil {
-		log.Fatal(err)
+		log.Print(err)
+		os.Exit(commandExitCode(app, os.Args))
 	}
 }
 
+// commandExitCode returns the exit status for an error returned by app.Run,
+// such as a missing or unparsable flag. Commands that report test outcomes
+// exit with exitSetupError so these errors can't be mistaken for failed tests.
+func commandExitCode(app *cli.App, args []string) int {
+	for _, arg := range args[1:] {
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if cmd := app.Command(arg); cmd != nil && (cmd.Name == "run" || cmd.Name == "snapshot") {
+			return exitSetupError
+		}
+		break
+	}
+	return 1
+}
+
 func runTests(c *cli.Context) error {
 	configFile := c.String("config")
 	verbose := c.Bool("verbose")
 
+	reports, err := parseReports(c.StringSlice("report"))
+	if err != nil {
+		return cli.Exit(err.Error(), exitSetupError)
+	}
+
+	// The JSON document replaces the human-readable output
+	format := c.String("format")
+	out := io.Writer(os.Stdout)
+	switch format {
+	case "text":
+	case "json":
+		out = io.Discard
+	default:
+		return cli.Exit(fmt.Sprintf("unsupported output format '%s'", format), exitSetupError)
+	}
+
 	// Parse the test configuration
 	testConfig, err := config.ParseTestConfig(configFile)
 	if err != nil {
-		return fmt.Errorf("failed to parse test configuration: %v", err)
+		return cli.Exit(fmt.Sprintf("failed to parse test configuration: %v", err), exitSetupError)
+	}
+	if err := setVars(c, testConfig); err != nil {
+		return cli.Exit(err.Error(), exitSetupError)
 	}
 
 	// Validate the test configuration
 	if err := testConfig.Validate(); err != nil {
-		return fmt.Errorf("invalid test configuration: %v", err)
+		return cli.Exit(fmt.Sprintf("invalid test configuration: %v", err), exitSetupError)
+	}
+
+	filter, err := newTestFilter(c)
+	if err != nil {
+		return cli.Exit(err.Error(), exitSetupError)
+	}
+	testConfig.Filter(filter)
+	if len(testConfig.Tests) == 0 {
+		return cli.Exit("no tests match the given filters", exitSetupError)
+	}
+
+	parallel := c.Int("parallel")
+	if parallel < 1 {
+		return cli.Exit("--parallel must be at least 1", exitSetupError)
 	}
 
 	// Create a new test runner
-	testRunner, err := runner.NewTestRunner()
+	testRunner, err := runner.NewTestRunner(runner.ProjectsFor(testConfig.Tests)...)
 	if err != nil {
-		return fmt.Errorf("failed to create test runner: %v", err)
+		return cli.Exit(fmt.Sprintf("failed to create test runner: %v", err), exitSetupError)
 	}
 	defer testRunner.Close()
 
 	// Run tests
+	start := time.Now()
+	summary := &runSummary{}
+	results := executeTests(testRunner, testConfig.Tests, parallel, func(test *models.Test) {
+		// Concurrent tests finish out of order, so only sequential runs announce them
+		if parallel == 1 {
+			fmt.Fprintf(out, "Running test: %s\n", test.Name)
+		}
+	}, func(result *testResult) {
+		summary.add(result)
+		printResult(out, result, verbose)
+	})
+	summary.duration = time.Since(start)
+
+	fmt.Fprintf(out, "Summary: %s\n", summary)
+
+	if format == "json" {
+		if err := writeJSONReport(os.Stdout, results, summary); err != nil {
+			return cli.Exit(fmt.Sprintf("failed to write JSON output: %v", err), exitSetupError)
+		}
+	}
+
+	if path, ok := reports["junit"]; ok {
+		if err := writeJUnitReport(path, "bqtest", start, results, summary); err != nil {
+			return cli.Exit(fmt.Sprintf("failed to write JUnit report: %v", err), exitSetupError)
+		}
+	}
+	if code := summary.exitCode(); code != 0 {
+		return cli.Exit("", code)
+	}
+	return nil
+}
+
+// printResult prints the human-readable outcome of a single test
+func printResult(out io.Writer, result *testResult, verbose bool) {
+	test := result.test
+
+	duration := result.duration.Round(time.Millisecond)
+	switch result.status {
+	case statusPassed:
+		fmt.Fprintf(out, "Test '%s' passed! (%s)\n", test.Name, duration)
+	case statusFailed:
+		fmt.Fprintf(out, "Test '%s' failed (%s). Differences:\n", test.Name, duration)
+		for _, diff := range result.differences {
+			fmt.Fprintln(out, diff)
+		}
+	case statusErrored:
+		fmt.Fprintf(out, "Test '%s' errored: %v (%s)\n", test.Name, result.err, duration)
+	}
+
+	if verbose {
+		fmt.Fprint(out, formatTables(result))
+	}
+
+	fmt.Fprintln(out)
+}
+
+func snapshotTests(c *cli.Context) error {
+	configFile := c.String("config")
+
+	// Parse the test configuration
+	testConfig, err := config.ParseTestConfig(configFile)
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("failed to parse test configuration: %v", err), exitSetupError)
+	}
+	if err := setVars(c, testConfig); err != nil {
+		return cli.Exit(err.Error(), exitSetupError)
+	}
+
+	// Validate the test configuration
+	if err := testConfig.Validate(); err != nil {
+		return cli.Exit(fmt.Sprintf("invalid test configuration: %v", err), exitSetupError)
+	}
+
+	// Create a new test runner
+	testRunner, err := runner.NewTestRunner(runner.ProjectsFor(testConfig.Tests)...)
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("failed to create test runner: %v", err), exitSetupError)
+	}
+	defer testRunner.Close()
+
+	var updated, unchanged, skipped, errored []string
+	var snapshots []snapshot
 	for _, test := range testConfig.Tests {
-		fmt.Printf("Running test: %s\n", test.Name)
+		fmt.Printf("Snapshotting test: %s\n", test.Name)
 
-		// Run the test query
+		// Inline expected rows live in the config, which is never rewritten
+		if test.ExpectedRows != nil {
+			fmt.Printf("Skipping test '%s': its expected rows are inline\n", test.Name)
+			skipped = append(skipped, test.Name)
+			continue
+		}
+
+		// Never overwrite the expected output of a test that didn't run
 		actualResults, err := testRunner.RunTest(&test)
 		if err != nil {
 			fmt.Printf("Error running test '%s': %v\n", test.Name, err)
+			errored = append(errored, test.Name)
 			continue
 		}
+		snapshots = append(snapshots, snapshot{test: test.Name, file: test.ExpectedOutput, table: actualResults.Table()})
+	}
 
-		// Get expected results
-		expectedResults, err := test.GetExpectedOutput()
-		if err != nil {
-			fmt.Printf("Error getting expected output for test '%s': %v\n", test.Name, err)
+	for _, group := range groupSnapshots(snapshots) {
+		// Cases inheriting their test's expected output share its file
+		if group.conflict {
+			fmt.Printf("Error writing expected output %s: tests %s share it but produced different results\n",
+				group.file, strings.Join(group.tests, ", "))
+			errored = append(errored, group.tests...)
 			continue
 		}
 
-		// Compare results
-		passed, differences := testRunner.CompareResults(actualResults, expectedResults)
-
-		if passed {
-			fmt.Printf("Test '%s' passed!\n", test.Name)
-		} else {
-			fmt.Printf("Test '%s' failed. Differences:\n", test.Name)
-			for _, diff := range differences {
-				fmt.Println(diff)
-			}
+		// A missing expected output is simply created
+		previous, err := fileutil.ReadCSVFile(group.file)
+		if err == nil && reflect.DeepEqual(previous, group.table) {
+			unchanged = append(unchanged, group.file)
+			continue
 		}
 
-		if verbose {
-			fmt.Printf("Actual results:\n%v\n", actualResults)
-			fmt.Printf("Expected results:\n%v\n", expectedResults)
+		if err := fileutil.WriteCSVFile(group.file, group.table); err != nil {
+			fmt.Printf("Error writing expected output %s: %v\n", group.file, err)
+			errored = append(errored, group.tests...)
+			continue
 		}
+		updated = append(updated, group.file)
+	}
 
-		fmt.Println()
+	fmt.Println()
+	fmt.Printf("Updated %d, unchanged %d, skipped %d, errored %d\n", len(updated), len(unchanged), len(skipped), len(errored))
+	for _, file := range updated {
+		fmt.Printf("  updated: %s\n", file)
+	}
+	for _, name := range skipped {
+		fmt.Printf("  skipped: %s\n", name)
+	}
+	for _, name := range errored {
+		fmt.Printf("  errored: %s\n", name)
 	}
 
+	if len(errored) > 0 {
+		return cli.Exit(fmt.Sprintf("%d tests errored; their expected outputs were left untouched", len(errored)), exitTestsErrored)
+	}
 	return nil
 }
 
+// snapshot is the actual result of a test, to be written to its expected output file
+type snapshot struct {
+	test  string
+	file  string
+	table [][]string
+}
+
+// snapshotGroup holds the snapshots of every test sharing an expected output file
+type snapshotGroup struct {
+	file     string
+	tests    []string
+	table    [][]string
+	conflict bool // the tests produced different results
+}
+
+// groupSnapshots groups snapshots by expected output file, in order of first
+// appearance. A file can only be rewritten when all its tests agree on the result.
+func groupSnapshots(snapshots []snapshot) []*snapshotGroup {
+	var groups []*snapshotGroup
+	byFile := make(map[string]*snapshotGroup)
+	for _, snap := range snapshots {
+		group, ok := byFile[snap.file]
+		if !ok {
+			group = &snapshotGroup{file: snap.file, table: snap.table}
+			byFile[snap.file] = group
+			groups = append(groups, group)
+		} else if !reflect.DeepEqual(group.table, snap.table) {
+			group.conflict = true
+		}
+		group.tests = append(group.tests, snap.test)
+	}
+	return groups
+}
+
 func listTests(c *cli.Context) error {
 	configFile := c.String("config")
 
@@ -129,10 +426,77 @@ func listTests(c *cli.Context) error {
 		return fmt.Errorf("failed to parse test configuration: %v", err)
 	}
 
+	filter, err := newTestFilter(c)
+	if err != nil {
+		return err
+	}
+	testConfig.Filter(filter)
+
 	fmt.Println("Available tests:")
 	for _, test := range testConfig.Tests {
-		fmt.Printf("- %s\n", test.Name)
+		if len(test.Tags) > 0 {
+			fmt.Printf("- %s [%s]\n", test.Name, strings.Join(test.Tags, ", "))
+		} else {
+			fmt.Printf("- %s\n", test.Name)
+		}
+		if test.Query != "" {
+			fmt.Printf("    query:    inline\n")
+		} else {
+			fmt.Printf("    query:    %s\n", test.QueryFile)
+		}
+		if test.Project != "" {
+			fmt.Printf("    project:  %s\n", test.Project)
+		}
+		for i, param := range test.Params {
+			name := "@" + param.Name
+			if param.Name == "" {
+				name = fmt.Sprintf("?%d", i+1)
+			}
+			fmt.Printf("    param:    %s %s = %s\n", name, param.Type, param.Value)
+		}
+		if test.ExpectedRows != nil {
+			fmt.Printf("    expected: inline\n")
+		} else {
+			fmt.Printf("    expected: %s\n", test.ExpectedOutput)
+		}
+		for _, input := range test.GetInputs() {
+			table := input.TableName
+			if input.Dataset != "" {
+				table = input.Dataset + "." + table
+				if input.Project != "" {
+					table = input.Project + "." + table
+				}
+			}
+			if input.Rows != nil {
+				fmt.Printf("    input:    inline (%s)\n", table)
+			} else {
+				fmt.Printf("    input:    %s (%s)\n", input.File, table)
+			}
+		}
+	}
+
+	return nil
+}
+
+func inferSchema(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("expected a single CSV file, got %d arguments", c.NArg())
 	}
 
+	records, err := fileutil.ReadCSVFile(c.Args().First())
+	if err != nil {
+		return fmt.Errorf("failed to read CSV file: %v", err)
+	}
+
+	schema, err := runner.InferCSVSchema(records, c.String("null-marker"))
+	if err != nil {
+		return fmt.Errorf("failed to infer schema: %v", err)
+	}
+
+	out, err := runner.SchemaJSON(schema)
+	if err != nil {
+		return fmt.Errorf("failed to encode schema: %v", err)
+	}
+	fmt.Println(string(out))
 	return nil
 }
@@ -1,6 +1,11 @@
 package main
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
 
 func TestMain(t *testing.T) {
 	t.Run("FirstTest", func(t *testing.T) {
@@ -9,3 +14,82 @@ func TestMain(t *testing.T) {
 		}
 	})
 }
+
+func TestRunSummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []testStatus
+		exitCode int
+	}{
+		{"All passed", []testStatus{statusPassed, statusPassed}, 0},
+		{"One failed", []testStatus{statusPassed, statusFailed}, exitTestsFailed},
+		{"One errored", []testStatus{statusPassed, statusErrored}, exitTestsErrored},
+		{"Errors take precedence", []testStatus{statusFailed, statusErrored}, exitTestsErrored},
+		{"No tests", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := &runSummary{}
+			for _, status := range tt.statuses {
+				summary.add(&testResult{status: status})
+			}
+			if summary.total() != len(tt.statuses) {
+				t.Errorf("Expected %d tests, got %d", len(tt.statuses), summary.total())
+			}
+			if code := summary.exitCode(); code != tt.exitCode {
+				t.Errorf("Expected exit code %d, got %d", tt.exitCode, code)
+			}
+		})
+	}
+}
+
+func TestCommandExitCode(t *testing.T) {
+	app := &cli.App{
+		Commands: []*cli.Command{
+			{Name: "run", Aliases: []string{"r"}},
+			{Name: "snapshot", Aliases: []string{"s"}},
+			{Name: "list", Aliases: []string{"l"}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		exitCode int
+	}{
+		{"Run", []string{"bqtest", "run", "--parallel", "x"}, exitSetupError},
+		{"Run alias", []string{"bqtest", "r"}, exitSetupError},
+		{"Snapshot", []string{"bqtest", "snapshot"}, exitSetupError},
+		{"Other command", []string{"bqtest", "list"}, 1},
+		{"No command", []string{"bqtest", "--help"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := commandExitCode(app, tt.args); code != tt.exitCode {
+				t.Errorf("Expected exit code %d, got %d", tt.exitCode, code)
+			}
+		})
+	}
+}
+
+func TestGroupSnapshots(t *testing.T) {
+	counted := [][]string{{"count"}, {"2"}}
+	snapshots := []snapshot{
+		{test: "totals/january", file: "totals.csv", table: counted},
+		{test: "totals/february", file: "totals.csv", table: [][]string{{"count"}, {"3"}}},
+		{test: "users/active", file: "users.csv", table: counted},
+		{test: "users/all", file: "users.csv", table: counted},
+		{test: "orders", file: "orders.csv", table: counted},
+	}
+
+	expected := []*snapshotGroup{
+		{file: "totals.csv", tests: []string{"totals/january", "totals/february"}, table: counted, conflict: true},
+		{file: "users.csv", tests: []string{"users/active", "users/all"}, table: counted},
+		{file: "orders.csv", tests: []string{"orders"}, table: counted},
+	}
+	if groups := groupSnapshots(snapshots); !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Expected groups %+v, got %+v", expected, groups)
+	}
+}
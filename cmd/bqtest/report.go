@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/JoseTorrado/bqtest/pkg/runner"
+)
+
+// parseReports parses --report values of the form kind=path
+func parseReports(values []string) (map[string]string, error) {
+	reports := make(map[string]string)
+	for _, value := range values {
+		kind, path, ok := strings.Cut(value, "=")
+		if !ok || path == "" {
+			return nil, fmt.Errorf("invalid report '%s': expected kind=path", value)
+		}
+		switch kind {
+		case "junit":
+			reports[kind] = path
+		default:
+			return nil, fmt.Errorf("unsupported report kind '%s'", kind)
+		}
+	}
+	return reports, nil
+}
+
+// formatTables renders the actual and expected tables as printed by --verbose
+func formatTables(result *testResult) string {
+	if result.actual == nil {
+		return ""
+	}
+	return fmt.Sprintf("Actual results:\n%v\nExpected results:\n%v\n", result.actual.Table(), result.expected)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnitReport writes the results as a JUnit XML report with one testcase per test
+func writeJUnitReport(path, suiteName string, started time.Time, results []*testResult, summary *runSummary) error {
+	suite := junitTestSuite{
+		Name:      suiteName,
+		Tests:     summary.total(),
+		Failures:  summary.failed,
+		Errors:    summary.errored,
+		Time:      junitSeconds(summary.duration),
+		Timestamp: started.UTC().Format(time.RFC3339),
+	}
+
+	for _, result := range results {
+		testCase := junitTestCase{
+			Name:      result.test.Name,
+			ClassName: suiteName,
+			Time:      junitSeconds(result.duration),
+			SystemOut: formatTables(result),
+		}
+		if test := result.test; test.Case != "" {
+			// Group the cases of a test under it
+			testCase.Name = test.Case
+			testCase.ClassName = suiteName + "." + strings.TrimSuffix(test.Name, "/"+test.Case)
+		}
+		switch result.status {
+		case statusFailed:
+			lines := make([]string, len(result.differences))
+			for i, diff := range result.differences {
+				lines[i] = diff.String()
+			}
+			testCase.Failure = &junitMessage{
+				Message: fmt.Sprintf("%d differences", len(result.differences)),
+				Text:    strings.Join(lines, "\n"),
+			}
+		case statusErrored:
+			testCase.Error = &junitMessage{
+				Message: result.err.Error(),
+				Text:    result.err.Error(),
+			}
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	report := junitTestSuites{
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), append(data, '\n')...), 0644)
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+type jsonReport struct {
+	Tests   []jsonTestResult `json:"tests"`
+	Summary jsonSummary      `json:"summary"`
+}
+
+type jsonTestResult struct {
+	Name        string              `json:"name"`
+	Case        string              `json:"case,omitempty"`
+	Status      testStatus          `json:"status"`
+	Duration    float64             `json:"duration_seconds"`
+	Query       string              `json:"query,omitempty"`
+	Error       string              `json:"error,omitempty"`
+	Actual      *jsonTable          `json:"actual,omitempty"`
+	Expected    *jsonTable          `json:"expected,omitempty"`
+	Differences []runner.Difference `json:"differences"`
+}
+
+// jsonTable holds the rows of a table with nil for NULL cells
+type jsonTable struct {
+	Columns []string    `json:"columns"`
+	Rows    [][]*string `json:"rows"`
+}
+
+type jsonSummary struct {
+	Total    int     `json:"total"`
+	Passed   int     `json:"passed"`
+	Failed   int     `json:"failed"`
+	Errored  int     `json:"errored"`
+	Duration float64 `json:"duration_seconds"`
+}
+
+// newExpectedJSONTable splits the expected output of a test into columns and
+// rows, reading NULL cells as the comparison with the actual results did
+func newExpectedJSONTable(result *testResult) *jsonTable {
+	if len(result.expected) == 0 {
+		return nil
+	}
+	var schema bigquery.Schema
+	if result.actual != nil {
+		schema = result.actual.Schema
+	}
+	opts := runner.NewCompareOptions(result.test)
+	return &jsonTable{Columns: result.expected[0], Rows: runner.ExpectedValues(result.expected, schema, opts)}
+}
+
+// writeJSONReport writes the results as a single JSON document
+func writeJSONReport(w io.Writer, results []*testResult, summary *runSummary) error {
+	report := jsonReport{
+		Tests: []jsonTestResult{},
+		Summary: jsonSummary{
+			Total:    summary.total(),
+			Passed:   summary.passed,
+			Failed:   summary.failed,
+			Errored:  summary.errored,
+			Duration: summary.duration.Seconds(),
+		},
+	}
+
+	for _, result := range results {
+		entry := jsonTestResult{
+			Name:        result.test.Name,
+			Case:        result.test.Case,
+			Status:      result.status,
+			Duration:    result.duration.Seconds(),
+			Query:       result.query,
+			Expected:    newExpectedJSONTable(result),
+			Differences: result.differences,
+		}
+		if entry.Differences == nil {
+			entry.Differences = []runner.Difference{}
+		}
+		if result.err != nil {
+			entry.Error = result.err.Error()
+		}
+		if result.actual != nil {
+			entry.Actual = &jsonTable{Columns: result.actual.Header(), Rows: result.actual.Values()}
+		}
+		report.Tests = append(report.Tests, entry)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/JoseTorrado/bqtest/pkg/models"
+	"github.com/JoseTorrado/bqtest/pkg/runner"
+)
+
+func TestParseReports(t *testing.T) {
+	reports, err := parseReports([]string{"junit=out/report.xml"})
+	if err != nil {
+		t.Fatalf("Failed to parse reports: %v", err)
+	}
+	if reports["junit"] != "out/report.xml" {
+		t.Errorf("Expected junit report path, got %q", reports["junit"])
+	}
+
+	for _, value := range []string{"junit", "junit=", "html=report.html"} {
+		if _, err := parseReports([]string{value}); err == nil {
+			t.Errorf("Expected an error for report %q, got none", value)
+		}
+	}
+}
+
+func TestWriteJUnitReport(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "junit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	results := []*testResult{
+		{test: &models.Test{Name: "passing"}, status: statusPassed, duration: time.Second},
+		{test: &models.Test{Name: "failing"}, status: statusFailed, differences: []runner.Difference{{Kind: runner.ValueMismatch, Column: "a", Expected: stringPtr("1"), Actual: stringPtr("2")}}},
+		{test: &models.Test{Name: "erroring"}, status: statusErrored, err: errors.New("job failed")},
+	}
+	summary := &runSummary{}
+	for _, result := range results {
+		summary.add(result)
+	}
+
+	path := filepath.Join(tmpDir, "report.xml")
+	if err := writeJUnitReport(path, "bqtest", time.Now(), results, summary); err != nil {
+		t.Fatalf("Failed to write JUnit report: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report junitTestSuites
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Failed to parse JUnit report: %v", err)
+	}
+
+	if report.Tests != 3 || report.Failures != 1 || report.Errors != 1 {
+		t.Errorf("Unexpected totals: %+v", report)
+	}
+	cases := report.Suites[0].TestCases
+	if len(cases) != 3 {
+		t.Fatalf("Expected 3 testcases, got %d", len(cases))
+	}
+	if cases[0].Time != "1.000" || cases[0].Failure != nil {
+		t.Errorf("Unexpected passing testcase: %+v", cases[0])
+	}
+	if cases[1].Failure == nil || cases[1].Failure.Message != "1 differences" {
+		t.Errorf("Expected failure on failing testcase, got %+v", cases[1])
+	}
+	if cases[2].Error == nil || cases[2].Error.Message != "job failed" {
+		t.Errorf("Expected error on erroring testcase, got %+v", cases[2])
+	}
+}
+
+func TestWriteJSONReport(t *testing.T) {
+	results := []*testResult{
+		{
+			test:   &models.Test{Name: "failing"},
+			status: statusFailed,
+			query:  "SELECT a FROM `test_dataset.users`",
+			actual: &runner.Results{
+				Schema: bigquery.Schema{{Name: "a", Type: bigquery.StringFieldType}},
+				Rows:   [][]bigquery.Value{{"2"}, {nil}, {"x"}},
+			},
+			expected: [][]string{{"a"}, {"1"}, {""}},
+			differences: []runner.Difference{
+				{Kind: runner.ValueMismatch, Row: 0, Column: "a", Expected: stringPtr("1"), Actual: stringPtr("2")},
+				{Kind: runner.UnexpectedRow, Row: 1, Cells: []runner.Cell{{Column: "a", Value: nil}}},
+				{Kind: runner.ValueMismatch, Row: 2, Column: "a", Expected: stringPtr(""), Actual: stringPtr("x")},
+				{Kind: runner.ValueMismatch, Row: 3, Column: "a", Expected: stringPtr("y")},
+				{Kind: runner.MissingColumn, Row: -1, Column: "b"},
+			},
+		},
+		{test: &models.Test{Name: "erroring"}, status: statusErrored, err: errors.New("job failed")},
+	}
+	summary := &runSummary{}
+	for _, result := range results {
+		summary.add(result)
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSONReport(&buf, results, summary); err != nil {
+		t.Fatalf("Failed to write JSON report: %v", err)
+	}
+
+	var report jsonReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse JSON report: %v", err)
+	}
+
+	if report.Summary.Total != 2 || report.Summary.Failed != 1 || report.Summary.Errored != 1 {
+		t.Errorf("Unexpected summary: %+v", report.Summary)
+	}
+	failing := report.Tests[0]
+	if failing.Query == "" || failing.Expected == nil || len(failing.Expected.Rows) != 2 {
+		t.Errorf("Unexpected failing test entry: %+v", failing)
+	}
+	if rows := failing.Actual.Rows; len(rows) != 3 || rows[1][0] != nil || *rows[0][0] != "2" {
+		t.Errorf("Expected NULL actual cells to be nil, got %+v", failing.Actual)
+	}
+	if len(failing.Differences) != 5 || *failing.Differences[0].Actual != "2" {
+		t.Errorf("Unexpected differences: %+v", failing.Differences)
+	}
+	if diff := failing.Differences[3]; diff.Actual != nil {
+		t.Errorf("Expected a NULL actual value to be nil, got %+v", diff)
+	}
+	if cells := failing.Differences[1].Cells; len(cells) != 1 || cells[0].Column != "a" || cells[0].Value != nil {
+		t.Errorf("Expected the unexpected row's cells, got %+v", cells)
+	}
+	if !strings.Contains(buf.String(), `"value": null`) {
+		t.Errorf("Expected NULL cells to be written as null, got %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"expected": "",`) {
+		t.Errorf("Expected an empty expected value to be written, got %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"actual": null`) {
+		t.Errorf("Expected a NULL actual value to be written as null, got %s", buf.String())
+	}
+	if strings.Contains(buf.String(), `"actual": ""`) || strings.Count(buf.String(), `"actual": null`) != 1 {
+		t.Errorf("Expected differences without values to omit them, got %s", buf.String())
+	}
+	if report.Tests[1].Error != "job failed" {
+		t.Errorf("Expected error to be reported, got %q", report.Tests[1].Error)
+	}
+}
+
+func TestReportsAttributeCases(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "junit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	results := []*testResult{
+		{test: &models.Test{Name: "revenue/no orders", Case: "no orders"}, status: statusFailed},
+	}
+	summary := &runSummary{}
+	summary.add(results[0])
+
+	path := filepath.Join(tmpDir, "report.xml")
+	if err := writeJUnitReport(path, "bqtest", time.Now(), results, summary); err != nil {
+		t.Fatalf("Failed to write JUnit report: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var junit junitTestSuites
+	if err := xml.Unmarshal(data, &junit); err != nil {
+		t.Fatalf("Failed to parse JUnit report: %v", err)
+	}
+	testCase := junit.Suites[0].TestCases[0]
+	if testCase.Name != "no orders" || testCase.ClassName != "bqtest.revenue" {
+		t.Errorf("Expected the case to be grouped under its test, got %+v", testCase)
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSONReport(&buf, results, summary); err != nil {
+		t.Fatalf("Failed to write JSON report: %v", err)
+	}
+	var report jsonReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse JSON report: %v", err)
+	}
+	if report.Tests[0].Name != "revenue/no orders" || report.Tests[0].Case != "no orders" {
+		t.Errorf("Expected the case to be reported, got %+v", report.Tests[0])
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/JoseTorrado/bqtest/pkg/models"
+	"github.com/JoseTorrado/bqtest/pkg/runner"
+)
+
+// Exit codes returned by the run command
+const (
+	exitTestsFailed  = 1 // at least one test failed its comparison
+	exitSetupError   = 2 // invalid configuration or emulator setup error
+	exitTestsErrored = 3 // at least one test could not run, e.g. on a SQL or fixture error
+)
+
+// testStatus is the outcome of a single test
+type testStatus string
+
+const (
+	statusPassed  testStatus = "passed"
+	statusFailed  testStatus = "failed"
+	statusErrored testStatus = "errored"
+)
+
+// testResult records the outcome of a single test run
+type testResult struct {
+	test        *models.Test
+	status      testStatus
+	duration    time.Duration
+	err         error
+	query       string
+	differences []runner.Difference
+	actual      *runner.Results
+	expected    [][]string
+}
+
+// executeTest runs a single test and compares its results with the expected output
+func executeTest(testRunner *runner.TestRunner, test *models.Test) *testResult {
+	start := time.Now()
+	result := &testResult{test: test}
+	defer func() { result.duration = time.Since(start) }()
+
+	// Keep the rendered query for reports, even if the test fails to run
+	result.query, _ = testRunner.RenderQuery(test)
+
+	// Run the test query
+	actual, err := testRunner.RunTest(test)
+	if err != nil {
+		result.status = statusErrored
+		result.err = fmt.Errorf("error running test: %v", err)
+		return result
+	}
+	result.actual = actual
+
+	// Get expected results
+	expected, err := test.GetExpectedOutput()
+	if err != nil {
+		result.status = statusErrored
+		result.err = fmt.Errorf("error getting expected output: %v", err)
+		return result
+	}
+	result.expected = expected
+
+	// Compare results
+	passed, differences := testRunner.CompareResults(actual, expected, runner.NewCompareOptions(test))
+	result.differences = differences
+	if passed {
+		result.status = statusPassed
+	} else {
+		result.status = statusFailed
+	}
+	return result
+}
+
+// executeTests runs the tests with up to parallel tests in flight. started and
+// done are called once per test as it starts and completes, never concurrently.
+// Results are returned in test order.
+func executeTests(testRunner *runner.TestRunner, tests []models.Test, parallel int, started func(*models.Test), done func(*testResult)) []*testResult {
+	results := make([]*testResult, len(tests))
+	slots := make(chan struct{}, parallel)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := range tests {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			mu.Lock()
+			started(&tests[i])
+			mu.Unlock()
+
+			result := executeTest(testRunner, &tests[i])
+			results[i] = result
+
+			mu.Lock()
+			defer mu.Unlock()
+			done(result)
+		}(i)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// runSummary aggregates the outcome of a test run
+type runSummary struct {
+	passed   int
+	failed   int
+	errored  int
+	duration time.Duration
+}
+
+func (s *runSummary) add(result *testResult) {
+	switch result.status {
+	case statusPassed:
+		s.passed++
+	case statusFailed:
+		s.failed++
+	case statusErrored:
+		s.errored++
+	}
+}
+
+func (s *runSummary) total() int {
+	return s.passed + s.failed + s.errored
+}
+
+// exitCode returns the process exit code for the run. Errored tests take
+// precedence over failures, as they point at a broken test rather than a wrong query.
+func (s *runSummary) exitCode() int {
+	switch {
+	case s.errored > 0:
+		return exitTestsErrored
+	case s.failed > 0:
+		return exitTestsFailed
+	default:
+		return 0
+	}
+}
+
+func (s *runSummary) String() string {
+	return fmt.Sprintf("%d tests, %d passed, %d failed, %d errored in %s",
+		s.total(), s.passed, s.failed, s.errored, s.duration.Round(time.Millisecond))
+}
@@ -5,6 +5,7 @@ go 1.22.7
 toolchain go1.22.12
 
 require (
+	cloud.google.com/go v0.118.1
 	cloud.google.com/go/bigquery v1.66.2
 	github.com/goccy/bigquery-emulator v0.6.6
 	github.com/urfave/cli/v2 v2.27.5
@@ -14,7 +15,6 @@ require (
 
 require (
 	cel.dev/expr v0.19.2 // indirect
-	cloud.google.com/go v0.118.1 // indirect
 	cloud.google.com/go/auth v0.14.1 // indirect
 	cloud.google.com/go/auth/oauth2adapt v0.2.7 // indirect
 	cloud.google.com/go/compute/metadata v0.6.0 // indirect
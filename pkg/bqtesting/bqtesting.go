@@ -0,0 +1,75 @@
+// Package bqtesting runs bqtest configurations as Go tests, so SQL tests can
+// live in `go test` next to the pipeline code they cover:
+//
+//	func TestQueries(t *testing.T) {
+//		bqtesting.RunConfig(t, "testdata/config.yaml")
+//	}
+//
+// Every configured test becomes a parallel subtest, so `go test -run` selects
+// tests by name and `go test -parallel` bounds how many run at once.
+package bqtesting
+
+import (
+	"testing"
+
+	"github.com/JoseTorrado/bqtest/pkg/config"
+	"github.com/JoseTorrado/bqtest/pkg/models"
+	"github.com/JoseTorrado/bqtest/pkg/runner"
+)
+
+// RunConfig parses and validates the test configuration at path and runs each
+// of its tests as a subtest of t against a shared emulator, which is closed once
+// all subtests complete.
+func RunConfig(t *testing.T, path string) {
+	t.Helper()
+
+	testConfig, err := config.ParseTestConfig(path)
+	if err != nil {
+		t.Fatalf("failed to parse test configuration: %v", err)
+	}
+	if err := testConfig.Validate(); err != nil {
+		t.Fatalf("invalid test configuration: %v", err)
+	}
+
+	testRunner, err := runner.NewTestRunner(runner.ProjectsFor(testConfig.Tests)...)
+	if err != nil {
+		t.Fatalf("failed to create test runner: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := testRunner.Close(); err != nil {
+			t.Errorf("failed to close test runner: %v", err)
+		}
+	})
+
+	for i := range testConfig.Tests {
+		test := &testConfig.Tests[i]
+		t.Run(test.Name, func(t *testing.T) {
+			t.Parallel()
+			RunTest(t, testRunner, test)
+		})
+	}
+}
+
+// RunTest runs a single test and reports every difference between its actual
+// and expected results as a test error naming the test.
+func RunTest(t testing.TB, testRunner *runner.TestRunner, test *models.Test) {
+	t.Helper()
+
+	actual, err := testRunner.RunTest(test)
+	if err != nil {
+		t.Fatalf("error running test '%s': %v", test.Name, err)
+	}
+
+	expected, err := test.GetExpectedOutput()
+	if err != nil {
+		t.Fatalf("error getting expected output of test '%s': %v", test.Name, err)
+	}
+
+	passed, differences := testRunner.CompareResults(actual, expected, runner.NewCompareOptions(test))
+	if passed {
+		return
+	}
+	for _, diff := range differences {
+		t.Errorf("test '%s': %s", test.Name, diff)
+	}
+}
@@ -0,0 +1,109 @@
+package bqtesting
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JoseTorrado/bqtest/pkg/config"
+	"github.com/JoseTorrado/bqtest/pkg/runner"
+)
+
+func TestRunConfig(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bqtesting")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	yamlContent := `
+tests:
+  - name: "User Count"
+    query: SELECT country, COUNT(*) AS user_count FROM ${users} GROUP BY country
+    ordered: false
+    inputs:
+      - table_name: users
+        rows:
+          - {id: 1, country: Canada}
+          - {id: 2, country: Canada}
+          - {id: 3, country: USA}
+    expected_rows: |
+      | country | user_count |
+      | Canada  | 2          |
+      | USA     | 1          |
+  - name: "Same Table Name"
+    query: SELECT COUNT(*) AS user_count FROM ${users}
+    inputs:
+      - table_name: users
+        rows:
+          - {id: 1}
+    expected_rows:
+      - {user_count: 1}
+`
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	RunConfig(t, configPath)
+}
+
+// recordingTB records the errors reported through it instead of failing the test
+type recordingTB struct {
+	*testing.T
+	errors []string
+}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRunTestReportsDifferences(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bqtesting")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	yamlContent := `
+tests:
+  - name: "Wrong Count"
+    query: SELECT COUNT(*) AS user_count FROM ${users}
+    inputs:
+      - table_name: users
+        rows:
+          - {id: 1}
+    expected_rows:
+      - {user_count: 2}
+`
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	testConfig, err := config.ParseTestConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testRunner, err := runner.NewTestRunner(runner.ProjectsFor(testConfig.Tests)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer testRunner.Close()
+
+	tb := &recordingTB{T: t}
+	RunTest(tb, testRunner, &testConfig.Tests[0])
+
+	if len(tb.errors) != 1 {
+		t.Fatalf("Expected 1 reported error, got %d: %v", len(tb.errors), tb.errors)
+	}
+	for _, want := range []string{"Wrong Count", "Column 'user_count': expected '2', got '1'"} {
+		if !strings.Contains(tb.errors[0], want) {
+			t.Errorf("Expected the reported error to contain %q, got %q", want, tb.errors[0])
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/JoseTorrado/bqtest/pkg/models"
+)
+
+// expandCases replaces every test with cases or param sets by one test per
+// case, named test/case, with the case's overrides applied
+func expandCases(tests []models.Test) ([]models.Test, error) {
+	var expanded []models.Test
+	for _, test := range tests {
+		cases, err := testCases(test)
+		if err != nil {
+			return nil, fmt.Errorf("test '%s': %v", test.Name, err)
+		}
+		if len(cases) == 0 {
+			expanded = append(expanded, test)
+			continue
+		}
+		for _, c := range cases {
+			expanded = append(expanded, applyCase(test, c))
+		}
+	}
+	return expanded, nil
+}
+
+// testCases returns the cases of the test, with its param sets as cases that
+// only override params and the expected output
+func testCases(test models.Test) ([]models.Case, error) {
+	if len(test.Cases) > 0 && len(test.ParamSets) > 0 {
+		return nil, errors.New("cases and param_sets cannot be combined")
+	}
+
+	cases := test.Cases
+	for _, set := range test.ParamSets {
+		if set.Name == "" {
+			return nil, errors.New("param set name cannot be empty")
+		}
+		if (set.ExpectedOutput == "") == (set.ExpectedRows == nil) {
+			return nil, fmt.Errorf("param set '%s' must declare exactly one of expected_output and expected_rows", set.Name)
+		}
+		cases = append(cases, models.Case{
+			Name:           set.Name,
+			Params:         set.Params,
+			ExpectedOutput: set.ExpectedOutput,
+			ExpectedRows:   set.ExpectedRows,
+		})
+	}
+
+	names := make(map[string]bool)
+	for _, c := range cases {
+		if c.Name == "" {
+			return nil, errors.New("case name cannot be empty")
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("duplicate case '%s'", c.Name)
+		}
+		names[c.Name] = true
+		if c.ExpectedOutput != "" && c.ExpectedRows != nil {
+			return nil, fmt.Errorf("case '%s' cannot declare both expected_output and expected_rows", c.Name)
+		}
+	}
+	return cases, nil
+}
+
+// applyCase returns a copy of the test with the case's overrides applied. The
+// copy doesn't share inputs or maps with the test, so it resolves its own paths
+// and inherits its own defaults.
+func applyCase(test models.Test, c models.Case) models.Test {
+	variant := test
+	variant.Name = test.Name + "/" + c.Name
+	variant.Case = c.Name
+	variant.Cases = nil
+	variant.ParamSets = nil
+	variant.TableMappings = maps.Clone(test.TableMappings)
+
+	variant.Vars = maps.Clone(test.Vars)
+	if len(c.Vars) > 0 {
+		if variant.Vars == nil {
+			variant.Vars = make(map[string]string)
+		}
+		maps.Copy(variant.Vars, c.Vars)
+	}
+	variant.Params = mergeParams(test.Params, c.Params)
+
+	variant.Inputs = slices.Clone(test.Inputs)
+	for _, input := range c.Inputs {
+		if variant.TableName != "" && input.TableName == variant.TableName {
+			// The case replaces the legacy table_name and input_file input
+			variant.TableName, variant.InputFile = "", ""
+		}
+		i := slices.IndexFunc(variant.Inputs, func(in models.Input) bool { return in.TableName == input.TableName })
+		if i >= 0 {
+			variant.Inputs[i] = input
+		} else {
+			variant.Inputs = append(variant.Inputs, input)
+		}
+	}
+
+	if c.ExpectedOutput != "" || c.ExpectedRows != nil {
+		variant.ExpectedOutput = c.ExpectedOutput
+		variant.ExpectedRows = c.ExpectedRows
+	}
+	return variant
+}
+
+// mergeParams returns the base parameters with overrides applied. Named
+// overrides replace the base parameter of the same name or are added, while
+// positional overrides replace all base parameters.
+func mergeParams(base, overrides []models.Param) []models.Param {
+	if len(overrides) > 0 && overrides[0].Name == "" {
+		return slices.Clone(overrides)
+	}
+
+	params := slices.Clone(base)
+	for _, override := range overrides {
+		i := slices.IndexFunc(params, func(p models.Param) bool { return p.Name == override.Name })
+		if i >= 0 {
+			params[i] = override
+		} else {
+			params = append(params, override)
+		}
+	}
+	return params
+}
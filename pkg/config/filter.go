@@ -0,0 +1,46 @@
+package config
+
+import (
+	"regexp"
+
+	"github.com/JoseTorrado/bqtest/pkg/models"
+)
+
+// TestFilter selects a subset of the configured tests
+type TestFilter struct {
+	Run         *regexp.Regexp // test names must match, if set
+	Tags        []string       // tests must have at least one of these tags, if set
+	ExcludeTags []string       // tests must have none of these tags
+}
+
+// Matches reports whether the test is selected by the filter
+func (f *TestFilter) Matches(test *models.Test) bool {
+	if f.Run != nil && !f.Run.MatchString(test.Name) {
+		return false
+	}
+	for _, tag := range f.ExcludeTags {
+		if test.HasTag(tag) {
+			return false
+		}
+	}
+	if len(f.Tags) == 0 {
+		return true
+	}
+	for _, tag := range f.Tags {
+		if test.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter keeps only the tests selected by the filter
+func (c *TestConfig) Filter(filter *TestFilter) {
+	var tests []models.Test
+	for _, test := range c.Tests {
+		if filter.Matches(&test) {
+			tests = append(tests, test)
+		}
+	}
+	c.Tests = tests
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/JoseTorrado/bqtest/pkg/models"
+)
+
+func TestConfigFilter(t *testing.T) {
+	config := &TestConfig{
+		Tests: []models.Test{
+			{Name: "User Count", Tags: []string{"users", "smoke"}},
+			{Name: "Order Totals", Tags: []string{"orders"}},
+			{Name: "User Orders", Tags: []string{"users", "orders", "slow"}},
+			{Name: "Untagged"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		filter   TestFilter
+		expected []string
+	}{
+		{"No filter", TestFilter{}, []string{"User Count", "Order Totals", "User Orders", "Untagged"}},
+		{"Name pattern", TestFilter{Run: regexp.MustCompile("^User")}, []string{"User Count", "User Orders"}},
+		{"Any tag", TestFilter{Tags: []string{"smoke", "orders"}}, []string{"User Count", "Order Totals", "User Orders"}},
+		{"Exclude tag", TestFilter{ExcludeTags: []string{"slow"}}, []string{"User Count", "Order Totals", "Untagged"}},
+		{"Combined", TestFilter{Run: regexp.MustCompile("Orders|Totals"), Tags: []string{"orders"}, ExcludeTags: []string{"slow"}}, []string{"Order Totals"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered := &TestConfig{Tests: append([]models.Test{}, config.Tests...)}
+			filtered.Filter(&tt.filter)
+
+			var names []string
+			for _, test := range filtered.Tests {
+				names = append(names, test.Name)
+			}
+			if len(names) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, names)
+			}
+			for i := range names {
+				if names[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, names)
+				}
+			}
+		})
+	}
+}
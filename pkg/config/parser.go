@@ -12,8 +12,24 @@ import (
 
 // TestConfig represents the structure of the YAML test config
 type TestConfig struct {
-	Tests    []models.Test `yaml:"tests"`
-	BasePath string        `yaml:"base_path"`
+	Tests      []models.Test     `yaml:"tests"`
+	BasePath   string            `yaml:"base_path"`
+	Ordered    *bool             `yaml:"ordered"`     // default for tests that don't set it
+	Tolerance  *models.Tolerance `yaml:"tolerance"`   // default for tests that don't set it
+	NullMarker *string           `yaml:"null_marker"` // default for tests that don't set it
+	// TableMappings apply to every test with an input of the mapped table name
+	TableMappings map[string]string `yaml:"table_mappings"`
+	// Vars are query variables of every test, unless the test sets them itself
+	Vars map[string]string `yaml:"vars"`
+}
+
+func (c *TestConfig) UnmarshalYAML(node *yaml.Node) error {
+	type plain TestConfig
+	if err := node.Decode((*plain)(c)); err != nil {
+		return err
+	}
+	c.NullMarker = models.NullMarkerOf(node, c.NullMarker)
+	return nil
 }
 
 func ParseTestConfig(filename string) (*TestConfig, error) {
@@ -37,11 +53,43 @@ func ParseTestConfig(filename string) (*TestConfig, error) {
 		}
 	}
 
+	if err := expandEnv(config.Vars); err != nil {
+		return nil, err
+	}
+
+	config.Tests, err = expandCases(config.Tests)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateTableMappings(config.TableMappings, config.Tests); err != nil {
+		return nil, err
+	}
+
 	for i, test := range config.Tests {
+		if err := expandEnv(test.Vars); err != nil {
+			return nil, fmt.Errorf("test '%s': %v", test.Name, err)
+		}
+		inheritVars(&config.Tests[i], config.Vars)
 		config.Tests[i].ResolvePaths(config.BasePath)
 		if test.SchemaOverrides == nil {
 			config.Tests[i].SchemaOverrides = make(map[string]string)
 		}
+		if test.Ordered == nil {
+			config.Tests[i].Ordered = config.Ordered
+		}
+		if test.Tolerance == nil {
+			config.Tests[i].Tolerance = config.Tolerance
+		}
+		if test.NullMarker == nil {
+			config.Tests[i].NullMarker = config.NullMarker
+		}
+		inheritTableMappings(&config.Tests[i], config.TableMappings)
+		for j, input := range test.Inputs {
+			if input.SchemaOverrides == nil {
+				config.Tests[i].Inputs[j].SchemaOverrides = make(map[string]string)
+			}
+		}
 	}
 
 	return &config, nil
@@ -49,6 +97,44 @@ func ParseTestConfig(filename string) (*TestConfig, error) {
 	// return nil, errors.New("Error froom insiide the function")
 }
 
+// validateTableMappings checks that every global table mapping is a valid
+// reference pointing at an input table of at least one test
+func validateTableMappings(mappings map[string]string, tests []models.Test) error {
+	tables := make(map[string]bool)
+	for _, test := range tests {
+		for _, input := range test.GetInputs() {
+			tables[input.TableName] = true
+		}
+	}
+	for ref, table := range mappings {
+		if err := models.ValidateTableRef(ref); err != nil {
+			return err
+		}
+		if !tables[table] {
+			return fmt.Errorf("table mapping '%s' points at '%s', which is not an input table of any test", ref, table)
+		}
+	}
+	return nil
+}
+
+// inheritTableMappings adds the global table mappings that point at one of the
+// test's inputs. Mappings of the test itself take precedence.
+func inheritTableMappings(test *models.Test, mappings map[string]string) {
+	tables := make(map[string]bool)
+	for _, input := range test.GetInputs() {
+		tables[input.TableName] = true
+	}
+	for ref, table := range mappings {
+		if _, ok := test.TableMappings[ref]; ok || !tables[table] {
+			continue
+		}
+		if test.TableMappings == nil {
+			test.TableMappings = make(map[string]string)
+		}
+		test.TableMappings[ref] = table
+	}
+}
+
 // Validate checks if the TestConfig is valid
 func (c *TestConfig) Validate() error {
 	if len(c.Tests) == 0 {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/JoseTorrado/bqtest/pkg/models"
@@ -61,12 +62,13 @@ tests:
 	}
 
 	expectedTest := models.Test{
-		Name:           "Test 1",
-		QueryFile:      "query1.sql",
-		ExpectedOutput: "expected1.csv",
+		Name:            "Test 1",
+		QueryFile:       filepath.Join(tmpDir, "query1.sql"),
+		ExpectedOutput:  filepath.Join(tmpDir, "expected1.csv"),
+		SchemaOverrides: map[string]string{},
 	}
 
-	if config.Tests[0] != expectedTest {
+	if !reflect.DeepEqual(config.Tests[0], expectedTest) {
 		t.Errorf("Test does not match. Got %+v, want %+v", config.Tests[0], expectedTest)
 	}
 
@@ -75,3 +77,536 @@ tests:
 		t.Errorf("Config validation failed: %v", err)
 	}
 }
+
+func TestParseTestConfigInputs(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bqtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	yamlContent := `
+tests:
+  - name: "Join Test"
+    query_file: "join.sql"
+    expected_output: "expected.csv"
+    inputs:
+      - table_name: users
+        file: inputs/users.csv
+        schema_overrides:
+          id: INTEGER
+      - table_name: orders
+        file: inputs/orders.csv
+`
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseTestConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to parse test config: %v", err)
+	}
+
+	inputs := config.Tests[0].Inputs
+	if len(inputs) != 2 {
+		t.Fatalf("Expected 2 inputs, got %d", len(inputs))
+	}
+	if inputs[0].File != filepath.Join(tmpDir, "inputs/users.csv") {
+		t.Errorf("Expected input path to be resolved, got %s", inputs[0].File)
+	}
+	if inputs[0].SchemaOverrides["id"] != "INTEGER" {
+		t.Errorf("Expected id override INTEGER, got %q", inputs[0].SchemaOverrides["id"])
+	}
+	if inputs[1].SchemaOverrides == nil {
+		t.Error("Expected schema overrides to be initialized")
+	}
+}
+
+func TestParseTestConfigOrdered(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bqtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	yamlContent := `
+ordered: false
+tests:
+  - name: "Inherits default"
+    query_file: "query1.sql"
+    expected_output: "expected1.csv"
+  - name: "Overrides default"
+    query_file: "query2.sql"
+    expected_output: "expected2.csv"
+    ordered: true
+`
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseTestConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to parse test config: %v", err)
+	}
+
+	if config.Tests[0].IsOrdered() {
+		t.Error("Expected first test to inherit ordered: false")
+	}
+	if !config.Tests[1].IsOrdered() {
+		t.Error("Expected second test to override ordered: true")
+	}
+}
+
+func TestParseTestConfigNullMarker(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bqtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	yamlContent := `
+null_marker: '\N'
+tests:
+  - name: "Inherits marker"
+    query_file: "query1.sql"
+    expected_output: "expected1.csv"
+  - name: "Overrides marker"
+    query_file: "query2.sql"
+    expected_output: "expected2.csv"
+    null_marker: NULL
+  - name: "Quoted marker"
+    query_file: "query3.sql"
+    expected_output: "expected3.csv"
+    null_marker: 'NULL'
+`
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseTestConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to parse test config: %v", err)
+	}
+
+	if marker := config.Tests[0].GetNullMarker(); marker != `\N` {
+		t.Errorf("Expected first test to inherit marker \\N, got %q", marker)
+	}
+	if marker := config.Tests[1].GetNullMarker(); marker != "NULL" {
+		t.Errorf("Expected unquoted NULL to override marker NULL, got %q", marker)
+	}
+	if marker := config.Tests[2].GetNullMarker(); marker != "NULL" {
+		t.Errorf("Expected quoted NULL to override marker NULL, got %q", marker)
+	}
+
+	yamlContent = `
+null_marker: NULL
+tests:
+  - name: "Inherits unquoted marker"
+    query_file: "query1.sql"
+    expected_output: "expected1.csv"
+`
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err = ParseTestConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to parse test config: %v", err)
+	}
+	if marker := config.Tests[0].GetNullMarker(); marker != "NULL" {
+		t.Errorf("Expected test to inherit unquoted marker NULL, got %q", marker)
+	}
+}
+
+func TestParseTestConfigInline(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bqtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	yamlContent := `
+tests:
+  - name: "Inline Test"
+    query: SELECT COUNT(*) AS count FROM ${users}
+    inputs:
+      - table_name: "users"
+        rows:
+          - {id: 1, name: Alice}
+          - {id: 2, name: Bob}
+    expected_rows: |
+      | count |
+      |-------|
+      | 2     |
+`
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseTestConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to parse test config: %v", err)
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Expected inline test to be valid, got %v", err)
+	}
+
+	test := config.Tests[0]
+	if test.QueryFile != "" || test.ExpectedOutput != "" || test.Inputs[0].File != "" {
+		t.Errorf("Expected no file paths to be resolved for inline data, got %+v", test)
+	}
+
+	expectedRows := [][]string{{"id", "name"}, {"1", "Alice"}, {"2", "Bob"}}
+	if rows := test.Inputs[0].Rows.Records(); !reflect.DeepEqual(rows, expectedRows) {
+		t.Errorf("Expected input rows %v, got %v", expectedRows, rows)
+	}
+
+	expected, err := test.GetExpectedOutput()
+	if err != nil {
+		t.Fatalf("Failed to get expected output: %v", err)
+	}
+	if !reflect.DeepEqual(expected, [][]string{{"count"}, {"2"}}) {
+		t.Errorf("Expected inline expected rows, got %v", expected)
+	}
+}
+
+func TestParseTestConfigTableMappings(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bqtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	yamlContent := `
+table_mappings:
+  prod.analytics.users: users
+  prod.analytics.orders: orders
+tests:
+  - name: "Users only"
+    query_file: "query1.sql"
+    expected_output: "expected1.csv"
+    inputs:
+      - table_name: users
+        file: users.csv
+  - name: "Overrides mapping"
+    query_file: "query2.sql"
+    expected_output: "expected2.csv"
+    inputs:
+      - table_name: users
+        file: users.csv
+      - table_name: staged_users
+        file: staged_users.csv
+    table_mappings:
+      prod.analytics.users: staged_users
+  - name: "Orders only"
+    query_file: "query3.sql"
+    expected_output: "expected3.csv"
+    inputs:
+      - table_name: orders
+        file: orders.csv
+`
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseTestConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to parse test config: %v", err)
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Expected config to be valid, got %v", err)
+	}
+
+	expected := []map[string]string{
+		{"prod.analytics.users": "users"},
+		{"prod.analytics.users": "staged_users"},
+		{"prod.analytics.orders": "orders"},
+	}
+	for i, mappings := range expected {
+		if !reflect.DeepEqual(config.Tests[i].TableMappings, mappings) {
+			t.Errorf("Test %d: expected mappings %v, got %v", i, mappings, config.Tests[i].TableMappings)
+		}
+	}
+
+	for name, mappings := range map[string]string{
+		"Unknown table": "table_mappings:\n  prod.analytics.users: usres\n",
+		"Invalid ref":   "table_mappings:\n  users: users\n",
+	} {
+		yamlContent := mappings + `
+tests:
+  - name: "Users only"
+    query_file: "query1.sql"
+    expected_output: "expected1.csv"
+    inputs:
+      - table_name: users
+        file: users.csv
+`
+		if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ParseTestConfig(configPath); err == nil {
+			t.Errorf("%s: expected an error for the global table mapping, got none", name)
+		}
+	}
+}
+
+func TestParseTestConfigVars(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bqtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	t.Setenv("BQTEST_PROJECT", "prod-project")
+
+	yamlContent := `
+vars:
+  project: ${BQTEST_PROJECT}
+  threshold: "10"
+  price: "$$5 at $${BQTEST_PROJECT} in $$$BQTEST_PROJECT"
+  pattern: "^\\$1 costs $5 ${not an env}"
+tests:
+  - name: "Inherits vars"
+    query_file: "query1.sql"
+    expected_output: "expected1.csv"
+  - name: "Overrides vars"
+    query_file: "query2.sql"
+    expected_output: "expected2.csv"
+    vars:
+      threshold: "20"
+`
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseTestConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to parse test config: %v", err)
+	}
+	config.SetVars(map[string]string{"start_date": "2024-01-01"})
+
+	expected := []map[string]string{
+		{"project": "prod-project", "threshold": "10", "price": "$5 at ${BQTEST_PROJECT} in $prod-project", "pattern": `^\$1 costs $5 ${not an env}`, "start_date": "2024-01-01"},
+		{"project": "prod-project", "threshold": "20", "price": "$5 at ${BQTEST_PROJECT} in $prod-project", "pattern": `^\$1 costs $5 ${not an env}`, "start_date": "2024-01-01"},
+	}
+	for i, vars := range expected {
+		if !reflect.DeepEqual(config.Tests[i].Vars, vars) {
+			t.Errorf("Test %d: expected vars %v, got %v", i, vars, config.Tests[i].Vars)
+		}
+	}
+
+	t.Run("Unset environment variable", func(t *testing.T) {
+		content := "vars:\n  project: ${BQTEST_UNSET_VARIABLE}\ntests: []\n"
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ParseTestConfig(configPath); err == nil {
+			t.Error("Expected an error due to an unset environment variable, got none")
+		}
+	})
+}
+
+func TestParseVars(t *testing.T) {
+	vars, err := ParseVars([]string{"start_date=2024-01-01", "filter=a=b", "empty="})
+	if err != nil {
+		t.Fatalf("Failed to parse vars: %v", err)
+	}
+	expected := map[string]string{"start_date": "2024-01-01", "filter": "a=b", "empty": ""}
+	if !reflect.DeepEqual(vars, expected) {
+		t.Errorf("Expected vars %v, got %v", expected, vars)
+	}
+
+	if _, err := ParseVars([]string{"start_date"}); err == nil {
+		t.Error("Expected an error for a variable without value, got none")
+	}
+}
+
+func TestParseTestConfigParamSets(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bqtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	yamlContent := `
+tests:
+  - name: "Revenue"
+    query: "SELECT @start_date, @region"
+    params:
+      - name: start_date
+        type: DATE
+        value: 2024-01-01
+      - name: region
+        type: STRING
+        value: EU
+    param_sets:
+      - name: january
+        expected_output: january.csv
+      - name: us
+        params:
+          - name: region
+            type: STRING
+            value: US
+        expected_rows:
+          - {region: US}
+`
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseTestConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to parse test config: %v", err)
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Expected expanded tests to be valid, got %v", err)
+	}
+	if len(config.Tests) != 2 {
+		t.Fatalf("Expected 2 tests, got %d", len(config.Tests))
+	}
+
+	january, us := config.Tests[0], config.Tests[1]
+	if january.Name != "Revenue/january" || us.Name != "Revenue/us" {
+		t.Errorf("Expected tests named after their param sets, got '%s' and '%s'", january.Name, us.Name)
+	}
+	if january.ExpectedOutput != filepath.Join(tmpDir, "january.csv") || january.ExpectedRows != nil {
+		t.Errorf("Expected the january set to use its expected output, got '%s'", january.ExpectedOutput)
+	}
+	if us.ExpectedRows == nil || us.ExpectedOutput != "" {
+		t.Error("Expected the us set to use its expected rows")
+	}
+
+	regions := []string{january.Params[1].Value.String(), us.Params[1].Value.String()}
+	if !reflect.DeepEqual(regions, []string{"EU", "US"}) || len(us.Params) != 2 {
+		t.Errorf("Expected set params to override the test params, got %v", regions)
+	}
+
+	t.Run("Set without expected output", func(t *testing.T) {
+		content := "tests:\n  - name: t\n    query: SELECT 1\n    param_sets:\n      - name: a\n"
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ParseTestConfig(configPath); err == nil {
+			t.Error("Expected an error due to a param set without expected output, got none")
+		}
+	})
+}
+
+func TestParseTestConfigCases(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bqtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	yamlContent := `
+vars:
+  currency: EUR
+tests:
+  - name: "Revenue"
+    query_file: "revenue.sql"
+    expected_output: "revenue.csv"
+    vars:
+      start_date: "2024-01-01"
+    inputs:
+      - table_name: orders
+        file: orders.csv
+      - table_name: users
+        file: users.csv
+    cases:
+      - name: default
+      - name: refunds
+        vars:
+          start_date: "2024-02-01"
+        params:
+          - name: min_amount
+            type: NUMERIC
+            value: 10
+        inputs:
+          - table_name: orders
+            file: refunds.csv
+          - table_name: countries
+            rows:
+              - {code: US}
+        expected_output: "refunds.csv"
+`
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseTestConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to parse test config: %v", err)
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Expected expanded tests to be valid, got %v", err)
+	}
+	if len(config.Tests) != 2 {
+		t.Fatalf("Expected 2 tests, got %d", len(config.Tests))
+	}
+
+	base, refunds := config.Tests[0], config.Tests[1]
+	if base.Name != "Revenue/default" || base.Case != "default" || refunds.Name != "Revenue/refunds" || refunds.Case != "refunds" {
+		t.Errorf("Expected tests named after their cases, got '%s' and '%s'", base.Name, refunds.Name)
+	}
+
+	if base.ExpectedOutput != filepath.Join(tmpDir, "revenue.csv") || refunds.ExpectedOutput != filepath.Join(tmpDir, "refunds.csv") {
+		t.Errorf("Expected outputs '%s' and '%s'", base.ExpectedOutput, refunds.ExpectedOutput)
+	}
+	if !reflect.DeepEqual(base.Vars, map[string]string{"start_date": "2024-01-01", "currency": "EUR"}) {
+		t.Errorf("Expected the default case to keep the test vars, got %v", base.Vars)
+	}
+	if !reflect.DeepEqual(refunds.Vars, map[string]string{"start_date": "2024-02-01", "currency": "EUR"}) {
+		t.Errorf("Expected the case vars to override the test vars, got %v", refunds.Vars)
+	}
+	if len(base.Params) != 0 || len(refunds.Params) != 1 {
+		t.Errorf("Expected only the refunds case to have params, got %v and %v", base.Params, refunds.Params)
+	}
+
+	var tables []string
+	for _, input := range refunds.Inputs {
+		tables = append(tables, input.TableName)
+	}
+	if !reflect.DeepEqual(tables, []string{"orders", "users", "countries"}) {
+		t.Errorf("Expected the case inputs to replace or add tables, got %v", tables)
+	}
+	if filepath.Base(base.Inputs[0].File) != "orders.csv" || filepath.Base(refunds.Inputs[0].File) != "refunds.csv" {
+		t.Errorf("Expected the orders input to be replaced in the case only, got '%s' and '%s'", base.Inputs[0].File, refunds.Inputs[0].File)
+	}
+	if base.Inputs[1].File != filepath.Join(tmpDir, "users.csv") || refunds.Inputs[1].File != base.Inputs[1].File {
+		t.Errorf("Expected shared input paths to be resolved once per test, got '%s' and '%s'", base.Inputs[1].File, refunds.Inputs[1].File)
+	}
+
+	invalid := map[string]string{
+		"duplicate case": "    cases:\n      - name: a\n      - name: a\n",
+		"unnamed case":   "    cases:\n      - expected_output: a.csv\n",
+		"cases combined with param sets": "    cases:\n      - name: a\n    param_sets:\n" +
+			"      - name: b\n        expected_output: b.csv\n",
+	}
+	for reason, cases := range invalid {
+		t.Run(reason, func(t *testing.T) {
+			content := "tests:\n  - name: t\n    query: SELECT 1\n    expected_output: t.csv\n" + cases
+			if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ParseTestConfig(configPath); err == nil {
+				t.Errorf("Expected an error due to %s, got none", reason)
+			}
+		})
+	}
+}
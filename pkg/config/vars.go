@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/JoseTorrado/bqtest/pkg/models"
+)
+
+// ParseVars parses key=value pairs, as given to the --var flag
+func ParseVars(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid variable '%s', expected key=value", pair)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// SetVars sets variables on every test, replacing the configured values
+func (c *TestConfig) SetVars(vars map[string]string) {
+	for i := range c.Tests {
+		for name, value := range vars {
+			if c.Tests[i].Vars == nil {
+				c.Tests[i].Vars = make(map[string]string)
+			}
+			c.Tests[i].Vars[name] = value
+		}
+	}
+}
+
+// inheritVars adds the global variables the test doesn't define itself
+func inheritVars(test *models.Test, vars map[string]string) {
+	for name, value := range vars {
+		if _, ok := test.Vars[name]; ok {
+			continue
+		}
+		if test.Vars == nil {
+			test.Vars = make(map[string]string)
+		}
+		test.Vars[name] = value
+	}
+}
+
+// expandEnv replaces $NAME and ${NAME} references to environment variables in
+// the values of vars, where NAME is a letter or underscore followed by letters,
+// digits and underscores. $$ is kept as a literal $, as is any other $, such as
+// the one in $5. Referencing an unset environment variable is an error.
+func expandEnv(vars map[string]string) error {
+	for name, value := range vars {
+		expanded, err := expandEnvValue(value)
+		if err != nil {
+			return fmt.Errorf("variable '%s': %v", name, err)
+		}
+		vars[name] = expanded
+	}
+	return nil
+}
+
+// expandEnvValue replaces the environment variable references in a single value
+func expandEnvValue(value string) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(value); {
+		var env string
+		switch {
+		case strings.HasPrefix(value[i:], "$$"):
+			out.WriteByte('$')
+			i += 2
+			continue
+		case strings.HasPrefix(value[i:], "${"):
+			end := strings.IndexByte(value[i:], '}')
+			if end < 0 || !models.IsIdentifier(value[i+2:i+end]) {
+				out.WriteString("${")
+				i += 2
+				continue
+			}
+			env = value[i+2 : i+end]
+			i += end + 1
+		case value[i] == '$' && envNameLen(value[i+1:]) > 0:
+			env = value[i+1 : i+1+envNameLen(value[i+1:])]
+			i += 1 + len(env)
+		default:
+			out.WriteByte(value[i])
+			i++
+			continue
+		}
+
+		envValue, ok := os.LookupEnv(env)
+		if !ok {
+			return "", fmt.Errorf("environment variable '%s' is not set (write $$ for a literal $)", env)
+		}
+		out.WriteString(envValue)
+	}
+	return out.String(), nil
+}
+
+// envNameLen returns the length of the environment variable name s starts with
+func envNameLen(s string) int {
+	n := 0
+	for n < len(s) && models.IsIdentifier(s[:n+1]) {
+		n++
+	}
+	return n
+}
@@ -1,7 +1,10 @@
 package fileutil
 
 import (
+	"bytes"
 	"encoding/csv"
+	"encoding/json"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,14 +16,17 @@ func ReadSQLFile(filename string) (string, error) {
 		return "", err
 	}
 
-	// Trim whitespace and end with ';'
-	query := strings.TrimSpace(string(content))
+	return NormalizeSQL(string(content)), nil
+}
+
+// NormalizeSQL trims whitespace around a query and ends it with ';', so queries
+// behave the same whether they are read from a file or written inline
+func NormalizeSQL(query string) string {
+	query = strings.TrimSpace(query)
 	if !strings.HasSuffix(query, ";") {
 		query += ";"
 	}
-
-	return query, nil
-
+	return query
 }
 
 func ReadSQLFiles(filenames ...string) (map[string]string, error) {
@@ -53,3 +59,58 @@ func ReadCSVFile(filename string) ([][]string, error) {
 
 	return records, nil
 }
+
+// WriteCSVFile writes the records to filename, creating parent directories as needed
+func WriteCSVFile(filename string, records [][]string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.WriteAll(records); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+// ReadJSONFile reads JSON objects from filename. The file may hold a single JSON
+// array of objects or a stream of objects, one per line (NDJSON). Numbers are
+// kept as json.Number so integers don't lose precision.
+func ReadJSONFile(filename string) ([]map[string]interface{}, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []map[string]interface{}
+	if trimmed := bytes.TrimSpace(content); len(trimmed) > 0 && trimmed[0] == '[' {
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.UseNumber()
+		if err := decoder.Decode(&records); err != nil {
+			return nil, err
+		}
+		return records, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	for {
+		var record map[string]interface{}
+		err := decoder.Decode(&record)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
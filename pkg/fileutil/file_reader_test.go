@@ -1,6 +1,7 @@
 package fileutil
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -35,6 +36,19 @@ func TestReadSQLFile(t *testing.T) {
 
 }
 
+func TestNormalizeSQL(t *testing.T) {
+	tests := map[string]string{
+		"SELECT 1":        "SELECT 1;",
+		"  SELECT 1;\n":   "SELECT 1;",
+		"\nSELECT\n  1\n": "SELECT\n  1;",
+	}
+	for query, expected := range tests {
+		if got := NormalizeSQL(query); got != expected {
+			t.Errorf("Expected %q, got %q", expected, got)
+		}
+	}
+}
+
 func TestReadSQLFiles(t *testing.T) {
 	// Temp directory for our files
 	tmpDir, err := os.MkdirTemp("", "sqltest")
@@ -104,3 +118,66 @@ func TestReadCSVFile(t *testing.T) {
 	}
 
 }
+
+func TestWriteCSVFile(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "csvtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	records := [][]string{
+		{"column1", "column2"},
+		{"value1", "value, with comma"},
+	}
+
+	// Parent directories are created on demand
+	csvFilePath := filepath.Join(tmpDir, "expected", "test.csv")
+	if err := WriteCSVFile(csvFilePath, records); err != nil {
+		t.Fatalf("Failed to write CSV file: %v", err)
+	}
+
+	written, err := ReadCSVFile(csvFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read CSV file: %v", err)
+	}
+
+	if !reflect.DeepEqual(written, records) {
+		t.Errorf("Expected records %v, got %v", records, written)
+	}
+}
+
+func TestReadJSONFile(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "jsontest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"array.json":    `[{"id": 1, "tags": ["a", "b"]}, {"id": 2, "address": {"city": "Paris"}}]`,
+		"events.ndjson": "{\"id\": 1, \"tags\": [\"a\", \"b\"]}\n{\"id\": 2, \"address\": {\"city\": \"Paris\"}}\n",
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(tmpDir, name)
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			records, err := ReadJSONFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read JSON file: %v", err)
+			}
+
+			expected := []map[string]interface{}{
+				{"id": json.Number("1"), "tags": []interface{}{"a", "b"}},
+				{"id": json.Number("2"), "address": map[string]interface{}{"city": "Paris"}},
+			}
+			if !reflect.DeepEqual(records, expected) {
+				t.Errorf("Expected records %v, got %v", expected, records)
+			}
+		})
+	}
+}
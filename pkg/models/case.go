@@ -0,0 +1,23 @@
+package models
+
+// Case is a named variation of a test, expanded into a test of its own named
+// test/case when the configuration is parsed. Its vars and params override the
+// test's of the same name, its inputs replace the test's inputs of the same
+// table name, and its expected output, when set, replaces the test's.
+//
+//	cases:
+//	  - name: refunds only
+//	    vars:
+//	      start_date: 2024-02-01
+//	    inputs:
+//	      - table_name: orders
+//	        file: refunds.csv
+//	    expected_output: refunds_expected.csv
+type Case struct {
+	Name           string            `yaml:"name"`
+	Vars           map[string]string `yaml:"vars"`
+	Params         []Param           `yaml:"params"`
+	Inputs         []Input           `yaml:"inputs"`
+	ExpectedOutput string            `yaml:"expected_output"`
+	ExpectedRows   *InlineTable      `yaml:"expected_rows"`
+}
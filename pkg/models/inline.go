@@ -0,0 +1,194 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// InlineTable holds fixture rows written directly in the YAML config, either as
+// a list of maps or as a markdown-style table string:
+//
+//	rows:
+//	  - {id: 1, name: foo}
+//
+//	rows: |
+//	  | id | name |
+//	  |----|------|
+//	  | 1  | foo  |
+//
+// Cells are kept as text, exactly like the cells of a CSV file. YAML nulls and
+// columns missing from a row are NULL: they load as NULL into inputs and only
+// match NULL in expected rows. Markdown tables write NULL as the test's null
+// marker. A markdown table with only a header row expects a result without rows.
+type InlineTable struct {
+	records [][]string      // header row first
+	nulls   map[[2]int]bool // data row and column of YAML null cells, nil for markdown tables
+}
+
+// NewInlineTable creates an inline table from records whose first row is the header
+func NewInlineTable(records [][]string) *InlineTable {
+	return &InlineTable{records: records}
+}
+
+// Records returns the table with the header as the first row. NULL cells are empty.
+func (it *InlineTable) Records() [][]string {
+	return it.records
+}
+
+// RecordsWithNulls returns the table with the header as the first row and
+// NULL cells set to nullMarker
+func (it *InlineTable) RecordsWithNulls(nullMarker string) [][]string {
+	if len(it.nulls) == 0 {
+		return it.records
+	}
+	records := make([][]string, len(it.records))
+	for i, record := range it.records {
+		records[i] = slices.Clone(record)
+	}
+	for cell := range it.nulls {
+		records[cell[0]+1][cell[1]] = nullMarker
+	}
+	return records
+}
+
+// Nulls returns which cells of every data row are NULL, or nil when NULL cells
+// can only be written as the null marker, as in markdown tables
+func (it *InlineTable) Nulls() [][]bool {
+	if it.nulls == nil {
+		return nil
+	}
+	nulls := make([][]bool, len(it.records)-1)
+	for i := range nulls {
+		nulls[i] = make([]bool, len(it.records[0]))
+	}
+	for cell := range it.nulls {
+		nulls[cell[0]][cell[1]] = true
+	}
+	return nulls
+}
+
+// IsNull reports whether the cell in data row i, column j is NULL
+func (it *InlineTable) IsNull(i, j int) bool {
+	return it.nulls[[2]int{i, j}]
+}
+
+func (it *InlineTable) UnmarshalYAML(node *yaml.Node) error {
+	var err error
+	switch node.Kind {
+	case yaml.ScalarNode:
+		it.records, err = parseMarkdownTable(node.Value)
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			err = errors.New("inline rows cannot be an empty list; use a markdown table with only a header row for no rows")
+			break
+		}
+		it.records, it.nulls, err = parseRowMaps(node)
+	default:
+		err = errors.New("inline rows must be a list of maps or a markdown table")
+	}
+	if err != nil {
+		return fmt.Errorf("line %d: %v", node.Line, err)
+	}
+	return nil
+}
+
+// parseRowMaps converts a list of maps into records, along with the position
+// of their null cells. Columns are ordered by their first appearance, and
+// columns missing from a row are null.
+func parseRowMaps(node *yaml.Node) ([][]string, map[[2]int]bool, error) {
+	var header []string
+	columns := make(map[string]int)
+	var rows []map[string]string
+	for _, item := range node.Content {
+		if item.Kind != yaml.MappingNode {
+			return nil, nil, errors.New("each inline row must be a map of column to value")
+		}
+		row := make(map[string]string)
+		for i := 0; i+1 < len(item.Content); i += 2 {
+			key, value := item.Content[i], item.Content[i+1]
+			if value.Kind != yaml.ScalarNode {
+				return nil, nil, fmt.Errorf("column '%s' must hold a single value", key.Value)
+			}
+			if _, ok := columns[key.Value]; !ok {
+				columns[key.Value] = len(header)
+				header = append(header, key.Value)
+			}
+			if value.Tag != "!!null" {
+				row[key.Value] = value.Value
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	records := [][]string{header}
+	nulls := make(map[[2]int]bool)
+	for r, row := range rows {
+		record := make([]string, len(header))
+		for name, i := range columns {
+			if value, ok := row[name]; ok {
+				record[i] = value
+			} else {
+				nulls[[2]int{r, i}] = true
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nulls, nil
+}
+
+// parseMarkdownTable converts a markdown-style table into records. The
+// separator line below the header is optional; "\|" escapes a pipe in a cell.
+func parseMarkdownTable(s string) ([][]string, error) {
+	var records [][]string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || isSeparatorLine(line) {
+			continue
+		}
+		line = strings.TrimPrefix(line, "|")
+		if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+			line = strings.TrimSuffix(line, "|")
+		}
+
+		var record []string
+		for _, cell := range splitCells(line) {
+			record = append(record, strings.TrimSpace(cell))
+		}
+		if len(records) > 0 && len(record) != len(records[0]) {
+			return nil, fmt.Errorf("row %q has %d cells, expected %d", line, len(record), len(records[0]))
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("inline table is empty")
+	}
+	return records, nil
+}
+
+// isSeparatorLine reports whether line is a header separator such as |---|:--:|
+func isSeparatorLine(line string) bool {
+	return strings.Trim(line, "|:- ") == "" && strings.Contains(line, "-")
+}
+
+// splitCells splits a table line on unescaped pipes
+func splitCells(line string) []string {
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, cell.String())
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, cell.String())
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestInlineTableUnmarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		expected [][]string
+		wantErr  bool
+	}{
+		{
+			name: "Markdown table",
+			yaml: "rows: |\n  | id | name |\n  |----|------|\n  | 1  | foo  |\n  | 2  |      |\n",
+			expected: [][]string{
+				{"id", "name"},
+				{"1", "foo"},
+				{"2", ""},
+			},
+		},
+		{
+			name: "Markdown table with escaped pipe",
+			yaml: "rows: |\n  | id | expr |\n  | 1  | a \\| b |\n",
+			expected: [][]string{
+				{"id", "expr"},
+				{"1", "a | b"},
+			},
+		},
+		{
+			name:     "Markdown table without rows",
+			yaml:     "rows: |\n  | id | name |\n  |----|------|\n",
+			expected: [][]string{{"id", "name"}},
+		},
+		{
+			name:    "Markdown table with missing cell",
+			yaml:    "rows: |\n  | id | name |\n  | 1  |\n",
+			wantErr: true,
+		},
+		{
+			name: "List of maps",
+			yaml: "rows:\n  - {id: 1, name: foo}\n  - {id: 2, country: US}\n  - {id: 3, name: null}\n",
+			expected: [][]string{
+				{"id", "name", "country"},
+				{"1", "foo", ""},
+				{"2", "", "US"},
+				{"3", "", ""},
+			},
+		},
+		{
+			name:    "List of maps with nested value",
+			yaml:    "rows:\n  - {id: 1, tags: [a, b]}\n",
+			wantErr: true,
+		},
+		{
+			name:    "Empty list",
+			yaml:    "rows: []\n",
+			wantErr: true,
+		},
+		{
+			name:    "Mapping",
+			yaml:    "rows:\n  id: 1\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc struct {
+				Rows *InlineTable `yaml:"rows"`
+			}
+			err := yaml.Unmarshal([]byte(tt.yaml), &doc)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to unmarshal inline rows: %v", err)
+			}
+			if !reflect.DeepEqual(doc.Rows.Records(), tt.expected) {
+				t.Errorf("Expected records %v, got %v", tt.expected, doc.Rows.Records())
+			}
+		})
+	}
+}
+
+func TestInlineTableNulls(t *testing.T) {
+	var doc struct {
+		Rows *InlineTable `yaml:"rows"`
+	}
+	content := "rows:\n  - {id: 1, name: null}\n  - {id: 2, name: \"\"}\n  - {id: 3}\n"
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		t.Fatalf("Failed to unmarshal inline rows: %v", err)
+	}
+
+	if !doc.Rows.IsNull(0, 1) || doc.Rows.IsNull(1, 1) || doc.Rows.IsNull(0, 0) || !doc.Rows.IsNull(2, 1) {
+		t.Error("Expected only the null and missing names to be NULL")
+	}
+	expected := [][]string{{"id", "name"}, {"1", "NULL"}, {"2", ""}, {"3", "NULL"}}
+	if got := doc.Rows.RecordsWithNulls("NULL"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected records %v, got %v", expected, got)
+	}
+	if got := doc.Rows.Records(); got[1][1] != "" {
+		t.Errorf("Expected the records to be left untouched, got %v", got)
+	}
+
+	expectedNulls := [][]bool{{false, true}, {false, false}, {false, true}}
+	if got := doc.Rows.Nulls(); !reflect.DeepEqual(got, expectedNulls) {
+		t.Errorf("Expected nulls %v, got %v", expectedNulls, got)
+	}
+	if got := NewInlineTable([][]string{{"id"}, {"1"}}).Nulls(); got != nil {
+		t.Errorf("Expected markdown-style tables to have no nulls, got %v", got)
+	}
+
+	marker := "NULL"
+	test := Test{ExpectedRows: doc.Rows, NullMarker: &marker}
+	records, err := test.GetExpectedOutput()
+	if err != nil {
+		t.Fatalf("Failed to get expected output: %v", err)
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("Expected rows %v, got %v", expected, records)
+	}
+}
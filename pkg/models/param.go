@@ -0,0 +1,137 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Param is a query parameter, referenced in the query as @name, or as ? in the
+// order of declaration when it has no name. Type is a standard SQL type such as
+// DATE, ARRAY<INT64> or STRUCT<id INT64, name STRING>.
+//
+//	params:
+//	  - name: start_date
+//	    type: DATE
+//	    value: 2024-01-01
+//	  - name: ids
+//	    type: ARRAY<INT64>
+//	    value: [1, 2, 3]
+type Param struct {
+	Name  string     `yaml:"name"`
+	Type  string     `yaml:"type"`
+	Value ParamValue `yaml:"value"`
+}
+
+// ParamValue is the value of a query parameter: a scalar kept as text, a list
+// of values for ARRAY parameters or a map of field values for STRUCT
+// parameters. The zero value is NULL.
+type ParamValue struct {
+	Scalar *string
+	Array  []ParamValue
+	Struct map[string]ParamValue
+}
+
+// IsNull reports whether the value is NULL
+func (pv ParamValue) IsNull() bool {
+	return pv.Scalar == nil && pv.Array == nil && pv.Struct == nil
+}
+
+// String formats the value the way it is written in YAML flow style
+func (pv ParamValue) String() string {
+	switch {
+	case pv.Scalar != nil:
+		return *pv.Scalar
+	case pv.Array != nil:
+		values := make([]string, len(pv.Array))
+		for i, v := range pv.Array {
+			values[i] = v.String()
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	case pv.Struct != nil:
+		names := make([]string, 0, len(pv.Struct))
+		for name := range pv.Struct {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fields := make([]string, len(names))
+		for i, name := range names {
+			fields[i] = name + ": " + pv.Struct[name].String()
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	default:
+		return "NULL"
+	}
+}
+
+func (pv *ParamValue) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Tag != "!!null" {
+			value := node.Value
+			pv.Scalar = &value
+		}
+	case yaml.SequenceNode:
+		pv.Array = []ParamValue{}
+		for _, item := range node.Content {
+			var value ParamValue
+			if err := item.Decode(&value); err != nil {
+				return err
+			}
+			pv.Array = append(pv.Array, value)
+		}
+	case yaml.MappingNode:
+		pv.Struct = make(map[string]ParamValue)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			var value ParamValue
+			if err := node.Content[i+1].Decode(&value); err != nil {
+				return err
+			}
+			pv.Struct[node.Content[i].Value] = value
+		}
+	case yaml.AliasNode:
+		return pv.UnmarshalYAML(node.Alias)
+	}
+	return nil
+}
+
+// ParamSet is one set of parameter values a test runs with. Its parameters
+// override the test's parameters of the same name, and each set is checked
+// against its own expected output.
+type ParamSet struct {
+	Name           string       `yaml:"name"`
+	Params         []Param      `yaml:"params"`
+	ExpectedOutput string       `yaml:"expected_output"`
+	ExpectedRows   *InlineTable `yaml:"expected_rows"`
+}
+
+// validateParams checks that parameters have a valid type and are either all
+// named, with unique names, or all positional
+func validateParams(params []Param) error {
+	names := make(map[string]bool)
+	for i, param := range params {
+		if param.Type == "" {
+			return fmt.Errorf("query parameter %d has no type", i+1)
+		}
+		if _, err := ParseParamType(param.Type); err != nil {
+			return fmt.Errorf("query parameter %d: %v", i+1, err)
+		}
+		if (param.Name == "") != (params[0].Name == "") {
+			return errors.New("query parameters must be either all named or all positional")
+		}
+		if param.Name == "" {
+			continue
+		}
+		if !IsIdentifier(param.Name) {
+			return fmt.Errorf("invalid query parameter name '%s'", param.Name)
+		}
+		if names[param.Name] {
+			return fmt.Errorf("duplicate query parameter '%s'", param.Name)
+		}
+		names[param.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParamUnmarshal(t *testing.T) {
+	yamlContent := `
+params:
+  - name: start_date
+    type: DATE
+    value: 2024-01-01
+  - name: ids
+    type: ARRAY<INT64>
+    value: [1, 2, 3]
+  - name: filter
+    type: STRUCT<min INT64, label STRING>
+    value: {min: 1, label: null}
+  - name: missing
+    type: STRING
+`
+	var config struct {
+		Params []Param `yaml:"params"`
+	}
+	if err := yaml.Unmarshal([]byte(yamlContent), &config); err != nil {
+		t.Fatalf("Failed to unmarshal params: %v", err)
+	}
+
+	expected := []string{"2024-01-01", "[1, 2, 3]", "{label: NULL, min: 1}", "NULL"}
+	if len(config.Params) != len(expected) {
+		t.Fatalf("Expected %d params, got %d", len(expected), len(config.Params))
+	}
+	for i, param := range config.Params {
+		if got := param.Value.String(); got != expected[i] {
+			t.Errorf("Param '%s': expected value %s, got %s", param.Name, expected[i], got)
+		}
+	}
+	if !config.Params[3].Value.IsNull() {
+		t.Error("Expected a param without value to be NULL")
+	}
+	if config.Params[1].Value.Array == nil || config.Params[2].Value.Struct == nil {
+		t.Error("Expected list and map values to be decoded as arrays and structs")
+	}
+}
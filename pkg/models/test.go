@@ -2,66 +2,298 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"path/filepath"
+	"slices"
+	"strings"
 
+	"cloud.google.com/go/bigquery"
 	"github.com/JoseTorrado/bqtest/pkg/fileutil"
+	"gopkg.in/yaml.v3"
 )
 
+// Input represents a single table that is loaded into the emulator before the query runs
+type Input struct {
+	TableName       string            `yaml:"table_name"`
+	File            string            `yaml:"file"`
+	Rows            *InlineTable      `yaml:"rows"` // alternative to file
+	SchemaOverrides map[string]string `yaml:"schema_overrides"`
+	Schema          string            `yaml:"schema"`       // optional bq-style JSON schema file
+	InferSchema     bool              `yaml:"infer_schema"` // infer CSV column types not in schema_overrides
+	Project         string            `yaml:"project"`      // project of the dataset, the test's project by default
+	Dataset         string            `yaml:"dataset"`      // load into this dataset rather than an isolated one
+}
+
+// Tolerance bounds the allowed difference between expected and actual FLOAT values.
+// Values match when they are within either the absolute or the relative tolerance.
+type Tolerance struct {
+	Absolute float64 `yaml:"absolute"`
+	Relative float64 `yaml:"relative"`
+}
+
 // Test represents a single BigQuery test case
 type Test struct {
 	Name            string            `yaml:"name"`
 	QueryFile       string            `yaml:"query_file"`
+	Query           string            `yaml:"query"`   // alternative to query_file
+	Project         string            `yaml:"project"` // default project of the query
 	SchemaOverrides map[string]string `yaml:"schema_overrides"`
 	InputFile       string            `yaml:"input_file"`
+	Inputs          []Input           `yaml:"inputs"`
 	ExpectedOutput  string            `yaml:"expected_output"`
+	ExpectedRows    *InlineTable      `yaml:"expected_rows"` // alternative to expected_output
 	TableName       string            `yaml:"table_name"`
+	Ordered         *bool             `yaml:"ordered"`
+	Tolerance       *Tolerance        `yaml:"tolerance"`
+	Tags            []string          `yaml:"tags"`
+	NullMarker      *string           `yaml:"null_marker"`
+	InferSchema     bool              `yaml:"infer_schema"`   // infer_schema for every CSV input
+	TableMappings   map[string]string `yaml:"table_mappings"` // production table reference to input table
+	Vars            map[string]string `yaml:"vars"`           // values of the ${name} variables of the query
+	Params          []Param           `yaml:"params"`         // query parameters
+	ParamSets       []ParamSet        `yaml:"param_sets"`     // expanded into one test per set when parsed
+	Cases           []Case            `yaml:"cases"`          // expanded into one test per case when parsed
+	Case            string            `yaml:"-"`              // name of the case or param set the test was expanded from
 	query           string            // cached query content
 	expectedData    [][]string        // cached expected output data
 }
 
+func (t *Test) UnmarshalYAML(node *yaml.Node) error {
+	type plain Test
+	if err := node.Decode((*plain)(t)); err != nil {
+		return err
+	}
+	t.NullMarker = NullMarkerOf(node, t.NullMarker)
+	return nil
+}
+
+// NullMarkerOf returns the null_marker of a mapping node, or marker when the
+// node doesn't set it to a bare null. YAML decodes an unquoted NULL, null or ~
+// as null rather than text, so such a marker is taken as written.
+func NullMarkerOf(node *yaml.Node, marker *string) *string {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Value == "null_marker" && value.Kind == yaml.ScalarNode && value.Tag == "!!null" && value.Value != "" {
+			written := value.Value
+			return &written
+		}
+	}
+	return marker
+}
+
+func (in *Input) Validate() error {
+	if in.TableName == "" {
+		return errors.New("table name cannot be empty")
+	}
+	if in.Rows != nil {
+		if in.File != "" {
+			return errors.New("input file and inline rows cannot be combined")
+		}
+		if len(in.Rows.Records()) < 2 {
+			return errors.New("inline rows must contain a header and at least one row")
+		}
+	} else {
+		if in.File == "" {
+			return errors.New("input file path cannot be empty")
+		}
+		switch filepath.Ext(in.File) {
+		case ".csv", ".json", ".ndjson":
+		default:
+			return errors.New("input file must have .csv, .json or .ndjson extension")
+		}
+	}
+	if in.Project != "" && in.Dataset == "" {
+		return errors.New("input project requires a dataset")
+	}
+	if in.Schema != "" {
+		if filepath.Ext(in.Schema) != ".json" {
+			return errors.New("schema file must have .json extension")
+		}
+		if len(in.SchemaOverrides) > 0 {
+			return errors.New("schema file and schema overrides cannot be combined")
+		}
+		if in.InferSchema {
+			return errors.New("schema file and schema inference cannot be combined")
+		}
+	}
+	for field, dataType := range in.SchemaOverrides {
+		if field == "" {
+			return errors.New("schema override field name cannot be empty")
+		}
+		if dataType == "" {
+			return errors.New("schema override data type cannot be empty")
+		}
+		fieldType, err := ParseFieldType(dataType)
+		if err != nil {
+			return fmt.Errorf("schema override for '%s': %v", field, err)
+		}
+		if fieldType == bigquery.RecordFieldType && (in.Rows != nil || filepath.Ext(in.File) == ".csv") {
+			return fmt.Errorf("schema override for '%s': RECORD fields are not supported in CSV inputs", field)
+		}
+	}
+	return nil
+}
+
+// GetInputs returns every input table of the test. The legacy single-table
+// fields (input_file, table_name, schema_overrides) are returned as the first input.
+func (t *Test) GetInputs() []Input {
+	if t.InputFile == "" && t.TableName == "" {
+		return t.Inputs
+	}
+	legacy := Input{
+		TableName:       t.TableName,
+		File:            t.InputFile,
+		SchemaOverrides: t.SchemaOverrides,
+		InferSchema:     t.InferSchema,
+	}
+	return append([]Input{legacy}, t.Inputs...)
+}
+
+// HasTag reports whether the test is labelled with tag
+func (t *Test) HasTag(tag string) bool {
+	for _, tt := range t.Tags {
+		if tt == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// GetNullMarker returns the text that stands for NULL in input and expected
+// CSV files. Without a marker, empty cells of non-STRING columns are NULL.
+func (t *Test) GetNullMarker() string {
+	if t.NullMarker == nil {
+		return ""
+	}
+	return *t.NullMarker
+}
+
+// IsOrdered reports whether rows must appear in the expected order.
+// Tests are ordered unless configured otherwise.
+func (t *Test) IsOrdered() bool {
+	return t.Ordered == nil || *t.Ordered
+}
+
 func (t *Test) ResolvePaths(basePath string) {
-	t.InputFile = filepath.Join(basePath, t.InputFile)
-	t.QueryFile = filepath.Join(basePath, t.QueryFile)
-	t.ExpectedOutput = filepath.Join(basePath, t.ExpectedOutput)
+	if t.InputFile != "" {
+		t.InputFile = filepath.Join(basePath, t.InputFile)
+	}
+	for i := range t.Inputs {
+		if t.Inputs[i].File != "" {
+			t.Inputs[i].File = filepath.Join(basePath, t.Inputs[i].File)
+		}
+		if t.Inputs[i].Schema != "" {
+			t.Inputs[i].Schema = filepath.Join(basePath, t.Inputs[i].Schema)
+		}
+	}
+	if t.QueryFile != "" {
+		t.QueryFile = filepath.Join(basePath, t.QueryFile)
+	}
+	if t.ExpectedOutput != "" {
+		t.ExpectedOutput = filepath.Join(basePath, t.ExpectedOutput)
+	}
 }
 
 func (t *Test) Validate() error {
 	if t.Name == "" {
 		return errors.New("Test name cannot be empty")
 	}
-	if t.QueryFile == "" {
-		return errors.New("Query file path cannot be empty")
+	if t.Query != "" {
+		if t.QueryFile != "" {
+			return errors.New("query file and inline query cannot be combined")
+		}
+	} else {
+		if t.QueryFile == "" {
+			return errors.New("query_file or query is required")
+		}
+		if filepath.Ext(t.QueryFile) != ".sql" {
+			return errors.New("query file must have .sql extension")
+		}
+	}
+	if t.ExpectedRows != nil {
+		if t.ExpectedOutput != "" {
+			return errors.New("expected output file and inline expected rows cannot be combined")
+		}
+	} else {
+		if t.ExpectedOutput == "" {
+			return errors.New("expected_output or expected_rows is required")
+		}
+		if filepath.Ext(t.ExpectedOutput) != ".csv" {
+			return errors.New("expected output file must have .csv extension")
+		}
 	}
-	if filepath.Ext(t.QueryFile) != ".sql" {
-		return errors.New("query file must have .sql extension")
+	if t.Tolerance != nil && (t.Tolerance.Absolute < 0 || t.Tolerance.Relative < 0) {
+		return errors.New("tolerance cannot be negative")
 	}
-	if t.ExpectedOutput == "" {
-		return errors.New("expected output file path cannot be empty")
+	for _, tag := range t.Tags {
+		if tag == "" {
+			return errors.New("tag cannot be empty")
+		}
 	}
-	if filepath.Ext(t.ExpectedOutput) != ".csv" {
-		return errors.New("expected output file must have .csv extension")
+	tables := make(map[string]bool)
+	for _, input := range t.GetInputs() {
+		if err := input.Validate(); err != nil {
+			return fmt.Errorf("invalid input '%s': %v", input.TableName, err)
+		}
+		if tables[input.TableName] {
+			return fmt.Errorf("duplicate input table '%s'", input.TableName)
+		}
+		tables[input.TableName] = true
 	}
-	if t.InputFile == "" {
-		return errors.New("input file path cannot be empty")
+	for name := range t.Vars {
+		if !IsIdentifier(name) {
+			return fmt.Errorf("invalid variable name '%s'", name)
+		}
+		// ${name} refers to the input table of that name, ${TABLE} to a single one
+		if tables[name] || (name == "TABLE" && len(tables) == 1) {
+			return fmt.Errorf("variable '%s' has the name of an input table", name)
+		}
 	}
-	if filepath.Ext(t.InputFile) != ".csv" {
-		return errors.New("input file must have .csv extension")
+	if err := validateParams(t.Params); err != nil {
+		return err
 	}
-	if t.TableName == "" {
-		return errors.New("table name cannot be empty")
+	if len(t.ParamSets) > 0 || len(t.Cases) > 0 {
+		return errors.New("cases and param_sets are only supported in test configuration files")
 	}
-	for field, dataType := range t.SchemaOverrides {
-		if field == "" {
-			return errors.New("schema override field name cannot be empty")
+	for ref, table := range t.TableMappings {
+		if err := ValidateTableRef(ref); err != nil {
+			return err
 		}
-		if dataType == "" {
-			return errors.New("schema override data type cannot be empty")
+		if !tables[table] {
+			return fmt.Errorf("table mapping '%s' points at unknown input table '%s'", ref, table)
 		}
 	}
 	return nil
 }
 
+// ValidateTableRef checks that the production table reference of a table
+// mapping has the dataset.table or project.dataset.table form
+func ValidateTableRef(ref string) error {
+	if parts := strings.Split(ref, "."); len(parts) < 2 || len(parts) > 3 || slices.Contains(parts, "") {
+		return fmt.Errorf("table mapping '%s' must be a dataset.table or project.dataset.table reference", ref)
+	}
+	return nil
+}
+
+// IsIdentifier reports whether name is a letter or underscore followed by
+// letters, digits and underscores, the form of query variable, query parameter
+// and environment variable names
+func IsIdentifier(name string) bool {
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
+
 func (t *Test) GetQuery() (string, error) {
+	if t.Query != "" {
+		return fileutil.NormalizeSQL(t.Query), nil
+	}
 	if t.query == "" {
 		var err error
 		t.query, err = fileutil.ReadSQLFile(t.QueryFile)
@@ -73,6 +305,9 @@ func (t *Test) GetQuery() (string, error) {
 }
 
 func (t *Test) GetExpectedOutput() ([][]string, error) {
+	if t.ExpectedRows != nil {
+		return t.ExpectedRows.RecordsWithNulls(t.GetNullMarker()), nil
+	}
 	if t.expectedData == nil {
 		var err error
 		t.expectedData, err = fileutil.ReadCSVFile(t.ExpectedOutput)
@@ -39,6 +39,29 @@ func TestTest(t *testing.T) {
 		}
 	})
 
+	t.Run("Valid Test - Inline Query", func(t *testing.T) {
+		test := Test{
+			Name:           "Valid Test",
+			Query:          "SELECT 1",
+			ExpectedOutput: "output.csv",
+		}
+		if err := test.Validate(); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("Invalid Test - Query File And Inline Query", func(t *testing.T) {
+		test := Test{
+			Name:           "Invalid Test",
+			QueryFile:      "query.sql",
+			Query:          "SELECT 1",
+			ExpectedOutput: "output.csv",
+		}
+		if err := test.Validate(); err == nil {
+			t.Error("Expected an error due to both query file and inline query, got none")
+		}
+	})
+
 	t.Run("Invalid Test - Wrong Query File Extension", func(t *testing.T) {
 		test := Test{
 			Name:           "Invalid Test",
@@ -60,6 +83,29 @@ func TestTest(t *testing.T) {
 		}
 	})
 
+	t.Run("Valid Test - Inline Expected Rows", func(t *testing.T) {
+		test := Test{
+			Name:         "Valid Test",
+			QueryFile:    "query.sql",
+			ExpectedRows: NewInlineTable([][]string{{"count"}, {"3"}}),
+		}
+		if err := test.Validate(); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("Invalid Test - Expected Output And Rows", func(t *testing.T) {
+		test := Test{
+			Name:           "Invalid Test",
+			QueryFile:      "query.sql",
+			ExpectedOutput: "output.csv",
+			ExpectedRows:   NewInlineTable([][]string{{"count"}, {"3"}}),
+		}
+		if err := test.Validate(); err == nil {
+			t.Error("Expected an error due to both expected output and expected rows, got none")
+		}
+	})
+
 	t.Run("Invalid Test - Wrong Expected Output Extension", func(t *testing.T) {
 		test := Test{
 			Name:           "Invalid Test",
@@ -72,6 +118,202 @@ func TestTest(t *testing.T) {
 	})
 }
 
+func TestGetInputs(t *testing.T) {
+	test := Test{
+		Name:           "Join Test",
+		QueryFile:      "query.sql",
+		ExpectedOutput: "output.csv",
+		InputFile:      "users.csv",
+		TableName:      "users",
+		Inputs: []Input{
+			{TableName: "orders", File: "orders.csv"},
+		},
+	}
+
+	inputs := test.GetInputs()
+	if len(inputs) != 2 {
+		t.Fatalf("Expected 2 inputs, got %d", len(inputs))
+	}
+	if inputs[0].TableName != "users" || inputs[0].File != "users.csv" {
+		t.Errorf("Expected legacy input first, got %+v", inputs[0])
+	}
+	if inputs[1].TableName != "orders" {
+		t.Errorf("Expected orders input second, got %+v", inputs[1])
+	}
+}
+
+func TestValidate(t *testing.T) {
+	date := "2024-01-01"
+	tests := []struct {
+		name      string
+		inputs    []Input
+		mappings  map[string]string
+		vars      map[string]string
+		params    []Param
+		cases     []Case
+		paramSets []ParamSet
+		wantErr   bool
+	}{
+		// Inputs
+		{
+			name:   "Multiple inputs",
+			inputs: []Input{{TableName: "users", File: "users.csv"}, {TableName: "orders", File: "orders.csv"}},
+		},
+		{
+			name:    "Duplicate table name",
+			inputs:  []Input{{TableName: "users", File: "users.csv"}, {TableName: "users", File: "more_users.csv"}},
+			wantErr: true,
+		},
+		{name: "Input without table name", inputs: []Input{{File: "users.csv"}}, wantErr: true},
+		{name: "Input without file", inputs: []Input{{TableName: "orders"}}, wantErr: true},
+		{name: "JSON input", inputs: []Input{{TableName: "events", File: "events.json"}}},
+		{name: "NDJSON input", inputs: []Input{{TableName: "events", File: "events.ndjson"}}},
+		{name: "Unsupported input extension", inputs: []Input{{TableName: "events", File: "events.parquet"}}, wantErr: true},
+		{name: "Input dataset", inputs: []Input{{TableName: "events", File: "events.csv", Dataset: "analytics"}}},
+		{name: "Input project and dataset", inputs: []Input{{TableName: "events", File: "events.csv", Project: "prod", Dataset: "analytics"}}},
+		{name: "Input project without dataset", inputs: []Input{{TableName: "events", File: "events.csv", Project: "prod"}}, wantErr: true},
+
+		// Inline inputs
+		{name: "Inline input", inputs: []Input{{TableName: "orders", Rows: NewInlineTable([][]string{{"id"}, {"1"}})}}},
+		{
+			name:    "Inline input with file",
+			inputs:  []Input{{TableName: "orders", File: "orders.csv", Rows: NewInlineTable([][]string{{"id"}, {"1"}})}},
+			wantErr: true,
+		},
+		{name: "Inline input without rows", inputs: []Input{{TableName: "orders", Rows: NewInlineTable([][]string{{"id"}})}}, wantErr: true},
+
+		// Schemas
+		{name: "Legacy type names", inputs: []Input{{TableName: "users", File: "users.csv", SchemaOverrides: map[string]string{"id": "INTEGER", "score": "FLOAT"}}}},
+		{
+			name:   "Standard SQL type names",
+			inputs: []Input{{TableName: "users", File: "users.csv", SchemaOverrides: map[string]string{"id": "int64", "amount": "BIGNUMERIC", "tags": "JSON"}}},
+		},
+		{name: "Unknown type name", inputs: []Input{{TableName: "users", File: "users.csv", SchemaOverrides: map[string]string{"id": "VARCHAR"}}}, wantErr: true},
+		{
+			name:    "RECORD override of a CSV input",
+			inputs:  []Input{{TableName: "users", File: "users.csv", SchemaOverrides: map[string]string{"address": "STRUCT"}}},
+			wantErr: true,
+		},
+		{
+			name:    "RECORD override of an inline input",
+			inputs:  []Input{{TableName: "users", Rows: NewInlineTable([][]string{{"address"}, {"x"}}), SchemaOverrides: map[string]string{"address": "RECORD"}}},
+			wantErr: true,
+		},
+		{name: "RECORD override of a JSON input", inputs: []Input{{TableName: "users", File: "users.json", SchemaOverrides: map[string]string{"address": "RECORD"}}}},
+		{name: "Empty type name", inputs: []Input{{TableName: "users", File: "users.csv", SchemaOverrides: map[string]string{"id": ""}}}, wantErr: true},
+		{name: "Schema file", inputs: []Input{{TableName: "events", File: "events.ndjson", Schema: "events_schema.json"}}},
+		{
+			name:    "Schema file without .json extension",
+			inputs:  []Input{{TableName: "events", File: "events.ndjson", Schema: "events_schema.yaml"}},
+			wantErr: true,
+		},
+		{
+			name:    "Schema file with overrides",
+			inputs:  []Input{{TableName: "events", File: "events.ndjson", Schema: "events_schema.json", SchemaOverrides: map[string]string{"id": "INTEGER"}}},
+			wantErr: true,
+		},
+		{name: "Schema inference", inputs: []Input{{TableName: "events", File: "events.csv", InferSchema: true}}},
+		{
+			name:   "Schema inference with overrides",
+			inputs: []Input{{TableName: "events", File: "events.csv", InferSchema: true, SchemaOverrides: map[string]string{"id": "STRING"}}},
+		},
+		{
+			name:    "Schema file with inference",
+			inputs:  []Input{{TableName: "events", File: "events.csv", Schema: "events_schema.json", InferSchema: true}},
+			wantErr: true,
+		},
+
+		// Table mappings
+		{
+			name:     "Table mappings",
+			inputs:   []Input{{TableName: "orders", File: "orders.csv"}},
+			mappings: map[string]string{"prod.sales.orders": "orders", "sales.orders": "orders"},
+		},
+		{
+			name:     "Table mapping to an unknown table",
+			inputs:   []Input{{TableName: "orders", File: "orders.csv"}},
+			mappings: map[string]string{"sales.customers": "customers"},
+			wantErr:  true,
+		},
+		{
+			name:     "Unqualified table mapping",
+			inputs:   []Input{{TableName: "orders", File: "orders.csv"}},
+			mappings: map[string]string{"orders": "orders"},
+			wantErr:  true,
+		},
+
+		// Vars
+		{name: "Vars", vars: map[string]string{"start_date": "2024-01-01", "_limit2": "10"}},
+		{name: "Invalid variable name", vars: map[string]string{"start-date": "2024-01-01"}, wantErr: true},
+		{name: "Variable name starting with a digit", vars: map[string]string{"2024": "2024-01-01"}, wantErr: true},
+		{
+			name:    "Variable named like an input table",
+			inputs:  []Input{{TableName: "users", File: "users.csv"}},
+			vars:    map[string]string{"users": "customers"},
+			wantErr: true,
+		},
+		{
+			name:    "Variable named TABLE",
+			inputs:  []Input{{TableName: "users", File: "users.csv"}},
+			vars:    map[string]string{"TABLE": "customers"},
+			wantErr: true,
+		},
+
+		// Params
+		{name: "Params", params: []Param{{Name: "start_date", Type: "DATE", Value: ParamValue{Scalar: &date}}}},
+		{name: "Positional params", params: []Param{{Type: "DATE", Value: ParamValue{Scalar: &date}}, {Type: "DATE"}}},
+		{
+			name:    "Mixed named and positional params",
+			params:  []Param{{Name: "start_date", Type: "DATE", Value: ParamValue{Scalar: &date}}, {Type: "DATE"}},
+			wantErr: true,
+		},
+		{name: "Duplicate param", params: []Param{{Name: "start_date", Type: "DATE"}, {Name: "start_date", Type: "DATE"}}, wantErr: true},
+		{name: "Param with an unknown type", params: []Param{{Name: "start_date", Type: "DATE_TIME"}}, wantErr: true},
+		{name: "Param with an invalid STRUCT type", params: []Param{{Name: "filter", Type: "STRUCT<INT64>"}}, wantErr: true},
+		{name: "Param without type", params: []Param{{Name: "start_date"}}, wantErr: true},
+
+		// Cases must be expanded before validation
+		{name: "Unexpanded cases", cases: []Case{{Name: "empty"}}, wantErr: true},
+		{name: "Unexpanded param sets", paramSets: []ParamSet{{Name: "january"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := Test{
+				Name:           "Validate Test",
+				Query:          "SELECT 1",
+				ExpectedOutput: "output.csv",
+				Inputs:         tt.inputs,
+				TableMappings:  tt.mappings,
+				Vars:           tt.vars,
+				Params:         tt.params,
+				Cases:          tt.cases,
+				ParamSets:      tt.paramSets,
+			}
+			err := test.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("Expected an error, got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestIsOrdered(t *testing.T) {
+	test := Test{Name: "Ordered Test"}
+	if !test.IsOrdered() {
+		t.Error("Expected tests to be ordered by default")
+	}
+
+	unordered := false
+	test.Ordered = &unordered
+	if test.IsOrdered() {
+		t.Error("Expected test to be unordered")
+	}
+}
+
 func TestGetQuery(t *testing.T) {
 	// Temp directory for our files
 	tmpDir, err := os.MkdirTemp("", "testquery")
@@ -122,6 +364,23 @@ func TestGetQuery(t *testing.T) {
 	}
 }
 
+func TestGetQueryInline(t *testing.T) {
+	test := Test{
+		Name:  "Inline Query",
+		Query: "\n  SELECT COUNT(*) AS count FROM ${users}\n",
+	}
+
+	// Inline queries are normalized like query files
+	query, err := test.GetQuery()
+	if err != nil {
+		t.Fatalf("Failed to get query: %v", err)
+	}
+	expected := "SELECT COUNT(*) AS count FROM ${users};"
+	if query != expected {
+		t.Errorf("Expected query %q, got %q", expected, query)
+	}
+}
+
 func TestGetExpectedOutput(t *testing.T) {
 	// Create a temporary directory
 	tmpDir, err := os.MkdirTemp("", "testexpectedoutput")
@@ -0,0 +1,194 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// maxFieldNameLength is the longest column name BigQuery accepts
+const maxFieldNameLength = 300
+
+// reservedFieldPrefixes are column name prefixes BigQuery keeps for itself
+var reservedFieldPrefixes = []string{"_TABLE_", "_FILE_", "_PARTITION", "_ROW_TIMESTAMP", "__ROOT__", "_COLIDENTIFIER"}
+
+// ValidateFieldName checks a column name against BigQuery's naming rules: only
+// letters, digits and underscores, not starting with a digit, at most 300
+// characters and without a reserved prefix.
+func ValidateFieldName(name string) error {
+	if name == "" {
+		return errors.New("column name cannot be empty")
+	}
+	if len(name) > maxFieldNameLength {
+		return fmt.Errorf("column name '%s' is longer than %d characters", name, maxFieldNameLength)
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9':
+			if i == 0 {
+				return fmt.Errorf("column name '%s' must start with a letter or underscore", name)
+			}
+		default:
+			return fmt.Errorf("column name '%s' may only contain letters, digits and underscores", name)
+		}
+	}
+	for _, prefix := range reservedFieldPrefixes {
+		if strings.HasPrefix(strings.ToUpper(name), prefix) {
+			return fmt.Errorf("column name '%s' uses the reserved prefix '%s'", name, prefix)
+		}
+	}
+	return nil
+}
+
+// fieldTypes maps legacy and standard SQL type names to BigQuery field types
+var fieldTypes = map[string]bigquery.FieldType{
+	"STRING":     bigquery.StringFieldType,
+	"BYTES":      bigquery.BytesFieldType,
+	"INTEGER":    bigquery.IntegerFieldType,
+	"INT64":      bigquery.IntegerFieldType,
+	"INT":        bigquery.IntegerFieldType,
+	"SMALLINT":   bigquery.IntegerFieldType,
+	"BIGINT":     bigquery.IntegerFieldType,
+	"TINYINT":    bigquery.IntegerFieldType,
+	"BYTEINT":    bigquery.IntegerFieldType,
+	"FLOAT":      bigquery.FloatFieldType,
+	"FLOAT64":    bigquery.FloatFieldType,
+	"NUMERIC":    bigquery.NumericFieldType,
+	"DECIMAL":    bigquery.NumericFieldType,
+	"BIGNUMERIC": bigquery.BigNumericFieldType,
+	"BIGDECIMAL": bigquery.BigNumericFieldType,
+	"BOOLEAN":    bigquery.BooleanFieldType,
+	"BOOL":       bigquery.BooleanFieldType,
+	"TIMESTAMP":  bigquery.TimestampFieldType,
+	"DATE":       bigquery.DateFieldType,
+	"TIME":       bigquery.TimeFieldType,
+	"DATETIME":   bigquery.DateTimeFieldType,
+	"GEOGRAPHY":  bigquery.GeographyFieldType,
+	"JSON":       bigquery.JSONFieldType,
+	"INTERVAL":   bigquery.IntervalFieldType,
+	"RECORD":     bigquery.RecordFieldType,
+	"STRUCT":     bigquery.RecordFieldType,
+}
+
+// ParseFieldType resolves a type name, case-insensitively, to its field type
+func ParseFieldType(typeString string) (bigquery.FieldType, error) {
+	fieldType, ok := fieldTypes[strings.ToUpper(strings.TrimSpace(typeString))]
+	if !ok {
+		return "", fmt.Errorf("unknown BigQuery type '%s'", typeString)
+	}
+	return fieldType, nil
+}
+
+// ParseParamType parses a standard SQL type such as INT64, ARRAY<DATE> or
+// STRUCT<id INT64, tags ARRAY<STRING>>
+func ParseParamType(s string) (*bigquery.StandardSQLDataType, error) {
+	dataType, rest, err := scanParamType(s)
+	if err == nil && strings.TrimSpace(rest) != "" {
+		err = fmt.Errorf("unexpected '%s'", strings.TrimSpace(rest))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid type '%s': %v", s, err)
+	}
+	return dataType, nil
+}
+
+// scanParamType parses the type at the start of s and returns the rest of s
+func scanParamType(s string) (*bigquery.StandardSQLDataType, string, error) {
+	name, rest := scanTypeWord(s)
+	switch strings.ToUpper(name) {
+	case "ARRAY":
+		rest, ok := scanTypeToken(rest, '<')
+		if !ok {
+			return nil, "", errors.New("expected ARRAY<element type>")
+		}
+		element, rest, err := scanParamType(rest)
+		if err != nil {
+			return nil, "", err
+		}
+		if element.ArrayElementType != nil {
+			return nil, "", errors.New("arrays of arrays are not supported")
+		}
+		if rest, ok = scanTypeToken(rest, '>'); !ok {
+			return nil, "", errors.New("missing '>' after the ARRAY element type")
+		}
+		return &bigquery.StandardSQLDataType{TypeKind: "ARRAY", ArrayElementType: element}, rest, nil
+
+	case "STRUCT":
+		rest, ok := scanTypeToken(rest, '<')
+		if !ok {
+			return nil, "", errors.New("expected STRUCT<name type, ...>")
+		}
+		structType := &bigquery.StandardSQLStructType{}
+		for {
+			var fieldName string
+			fieldName, rest = scanTypeWord(rest)
+			if err := ValidateFieldName(fieldName); err != nil {
+				return nil, "", err
+			}
+			fieldType, next, err := scanParamType(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			structType.Fields = append(structType.Fields, &bigquery.StandardSQLField{Name: fieldName, Type: fieldType})
+
+			if rest, ok = scanTypeToken(next, ','); ok {
+				continue
+			}
+			if rest, ok = scanTypeToken(next, '>'); !ok {
+				return nil, "", errors.New("missing '>' after the STRUCT fields")
+			}
+			return &bigquery.StandardSQLDataType{TypeKind: "STRUCT", StructType: structType}, rest, nil
+		}
+
+	default:
+		fieldType, err := ParseFieldType(name)
+		if err != nil {
+			return nil, "", err
+		}
+		if fieldType == bigquery.RecordFieldType {
+			return nil, "", errors.New("expected STRUCT<name type, ...>")
+		}
+		return &bigquery.StandardSQLDataType{TypeKind: typeKind(fieldType)}, rest, nil
+	}
+}
+
+// scanTypeWord returns the identifier at the start of s, after any spaces, and the rest of s
+func scanTypeWord(s string) (string, string) {
+	s = strings.TrimLeft(s, " \t\n")
+	i := 0
+	for i < len(s) && isTypeWordChar(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// isTypeWordChar reports whether c can be part of a type or field name
+func isTypeWordChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// scanTypeToken consumes c, after any spaces, from the start of s
+func scanTypeToken(s string, c byte) (string, bool) {
+	s = strings.TrimLeft(s, " \t\n")
+	if s == "" || s[0] != c {
+		return s, false
+	}
+	return s[1:], true
+}
+
+// typeKind returns the standard SQL name of a scalar field type
+func typeKind(fieldType bigquery.FieldType) string {
+	switch fieldType {
+	case bigquery.IntegerFieldType:
+		return "INT64"
+	case bigquery.FloatFieldType:
+		return "FLOAT64"
+	case bigquery.BooleanFieldType:
+		return "BOOL"
+	default:
+		return string(fieldType)
+	}
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+)
+
+func TestParseFieldType(t *testing.T) {
+	tests := map[string]bigquery.FieldType{
+		"INTEGER":    bigquery.IntegerFieldType,
+		"int64":      bigquery.IntegerFieldType,
+		"FLOAT64":    bigquery.FloatFieldType,
+		"Bool":       bigquery.BooleanFieldType,
+		"DECIMAL":    bigquery.NumericFieldType,
+		"BIGNUMERIC": bigquery.BigNumericFieldType,
+		"DATETIME":   bigquery.DateTimeFieldType,
+		"TIME":       bigquery.TimeFieldType,
+		"BYTES":      bigquery.BytesFieldType,
+		"GEOGRAPHY":  bigquery.GeographyFieldType,
+		"JSON":       bigquery.JSONFieldType,
+		"INTERVAL":   bigquery.IntervalFieldType,
+	}
+
+	for name, expected := range tests {
+		fieldType, err := ParseFieldType(name)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", name, err)
+			continue
+		}
+		if fieldType != expected {
+			t.Errorf("Expected %s to map to %s, got %s", name, expected, fieldType)
+		}
+	}
+
+	if _, err := ParseFieldType("VARCHAR"); err == nil {
+		t.Error("Expected an error for an unknown type, got none")
+	}
+}
+
+func TestValidateFieldName(t *testing.T) {
+	tests := map[string]bool{
+		"country":                true,
+		"Country":                true,
+		"_user_id2":              true,
+		"":                       false,
+		"2nd_place":              false,
+		"first name":             false,
+		"amount-usd":             false,
+		"_partitiontime":         false,
+		"_TABLE_SUFFIX":          false,
+		strings.Repeat("a", 300): true,
+		strings.Repeat("a", 301): false,
+	}
+
+	for name, valid := range tests {
+		err := ValidateFieldName(name)
+		if valid && err != nil {
+			t.Errorf("Expected %q to be valid, got %v", name, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("Expected an error for %q, got none", name)
+		}
+	}
+}
+
+func TestParseParamType(t *testing.T) {
+	tests := []struct {
+		typeString string
+		expected   *bigquery.StandardSQLDataType
+		wantErr    bool
+	}{
+		{typeString: "date", expected: &bigquery.StandardSQLDataType{TypeKind: "DATE"}},
+		{typeString: "INTEGER", expected: &bigquery.StandardSQLDataType{TypeKind: "INT64"}},
+		{
+			typeString: "ARRAY<STRING>",
+			expected: &bigquery.StandardSQLDataType{
+				TypeKind:         "ARRAY",
+				ArrayElementType: &bigquery.StandardSQLDataType{TypeKind: "STRING"},
+			},
+		},
+		{
+			typeString: "STRUCT<id INT64, tags ARRAY< STRING >>",
+			expected: &bigquery.StandardSQLDataType{
+				TypeKind: "STRUCT",
+				StructType: &bigquery.StandardSQLStructType{Fields: []*bigquery.StandardSQLField{
+					{Name: "id", Type: &bigquery.StandardSQLDataType{TypeKind: "INT64"}},
+					{Name: "tags", Type: &bigquery.StandardSQLDataType{
+						TypeKind:         "ARRAY",
+						ArrayElementType: &bigquery.StandardSQLDataType{TypeKind: "STRING"},
+					}},
+				}},
+			},
+		},
+		{typeString: "ARRAY<ARRAY<INT64>>", wantErr: true},
+		{typeString: "ARRAY<INT64", wantErr: true},
+		{typeString: "STRUCT<INT64>", wantErr: true},
+		{typeString: "RECORD", wantErr: true},
+		{typeString: "INT64 extra", wantErr: true},
+		{typeString: "UNKNOWN", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typeString, func(t *testing.T) {
+			got, err := ParseParamType(tt.typeString)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to parse type: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
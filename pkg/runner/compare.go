@@ -0,0 +1,601 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/JoseTorrado/bqtest/pkg/models"
+)
+
+// CompareOptions controls how actual results are matched against the expected output
+type CompareOptions struct {
+	// Ordered compares rows by position; otherwise rows are compared as multisets
+	Ordered bool
+	// AbsoluteTolerance and RelativeTolerance bound the allowed difference between FLOAT values
+	AbsoluteTolerance float64
+	RelativeTolerance float64
+	// NullMarker is the expected text of NULL values
+	NullMarker string
+	// Nulls marks the NULL cells of every expected data row, for expected
+	// outputs that don't write NULL as text. Set, it replaces the NullMarker.
+	Nulls [][]bool
+}
+
+// NewCompareOptions builds the comparison options configured for a test
+func NewCompareOptions(test *models.Test) CompareOptions {
+	opts := CompareOptions{Ordered: test.IsOrdered(), NullMarker: test.GetNullMarker()}
+	if test.Tolerance != nil {
+		opts.AbsoluteTolerance = test.Tolerance.Absolute
+		opts.RelativeTolerance = test.Tolerance.Relative
+	}
+	if test.ExpectedRows != nil {
+		opts.Nulls = test.ExpectedRows.Nulls()
+	}
+	return opts
+}
+
+// DifferenceKind identifies what a Difference describes
+type DifferenceKind string
+
+const (
+	MissingColumn    DifferenceKind = "missing_column"
+	UnexpectedColumn DifferenceKind = "unexpected_column"
+	RowCountMismatch DifferenceKind = "row_count"
+	ValueMismatch    DifferenceKind = "value"
+	MissingRow       DifferenceKind = "missing_row"
+	UnexpectedRow    DifferenceKind = "unexpected_row"
+	MissingHeader    DifferenceKind = "missing_header"
+)
+
+// Difference describes a single mismatch between the actual and expected results.
+// Row is the zero-based data row index (into the actual results for unexpected
+// rows, the expected output otherwise), or -1 when the difference isn't tied to a row.
+// Expected and Actual are nil for NULL values and for differences without
+// values, such as missing columns. Missing and unexpected rows hold their cells
+// rather than an expected or actual value. In JSON, value differences always
+// carry both values, with null for NULL.
+type Difference struct {
+	Kind     DifferenceKind `json:"kind"`
+	Row      int            `json:"row"`
+	Column   string         `json:"column,omitempty"`
+	Expected *string        `json:"expected,omitempty"`
+	Actual   *string        `json:"actual,omitempty"`
+	Cells    []Cell         `json:"cells,omitempty"`
+}
+
+// MarshalJSON writes NULL values of value differences as null rather than
+// omitting them, so they can be told apart from differences without values.
+func (d Difference) MarshalJSON() ([]byte, error) {
+	type difference Difference
+	if d.Kind != ValueMismatch {
+		return json.Marshal(difference(d))
+	}
+	return json.Marshal(struct {
+		difference
+		Expected *string `json:"expected"`
+		Actual   *string `json:"actual"`
+	}{difference(d), d.Expected, d.Actual})
+}
+
+// Cell is the value of a column in a missing or unexpected row. Value is nil for NULL.
+type Cell struct {
+	Column string  `json:"column"`
+	Value  *string `json:"value"`
+}
+
+func (d Difference) String() string {
+	switch d.Kind {
+	case MissingColumn:
+		return fmt.Sprintf("Missing column '%s'", d.Column)
+	case UnexpectedColumn:
+		return fmt.Sprintf("Unexpected column '%s'", d.Column)
+	case RowCountMismatch:
+		return fmt.Sprintf("Row count mismatch: expected %s, got %s", *d.Expected, *d.Actual)
+	case ValueMismatch:
+		return fmt.Sprintf("Row %d, Column '%s': expected %s, got %s", d.Row, d.Column, quoteValue(d.Expected), quoteValue(d.Actual))
+	case MissingRow:
+		return fmt.Sprintf("Missing row: %s", formatRow(d.Cells))
+	case UnexpectedRow:
+		return fmt.Sprintf("Unexpected row: %s", formatRow(d.Cells))
+	default:
+		return "Missing header row in expected results"
+	}
+}
+
+// column pairs a result field with its position in the actual and expected tables
+type column struct {
+	field       *bigquery.FieldSchema
+	actualPos   int
+	expectedPos int
+}
+
+// CompareResults compares the actual results with the expected output.
+// The expected output must start with a header row; columns are matched by
+// name (case-insensitively, like BigQuery) rather than by position, and each
+// expected value is parsed according to the type of the matching result column.
+func (r *TestRunner) CompareResults(actual *Results, expected [][]string, opts CompareOptions) (bool, []Difference) {
+	if len(expected) == 0 {
+		return false, []Difference{{Kind: MissingHeader, Row: -1}}
+	}
+
+	var differences []Difference
+
+	actualColumns := columnIndex(actual.Header())
+	expectedColumns := columnIndex(expected[0])
+
+	// Only the columns present on both sides take part in the row comparison
+	var columns []column
+	for j, name := range expected[0] {
+		k, ok := actualColumns[strings.ToLower(name)]
+		if !ok {
+			differences = append(differences, Difference{Kind: MissingColumn, Row: -1, Column: name})
+			continue
+		}
+		columns = append(columns, column{field: actual.Schema[k], actualPos: k, expectedPos: j})
+	}
+	for _, name := range actual.Header() {
+		if _, ok := expectedColumns[strings.ToLower(name)]; !ok {
+			differences = append(differences, Difference{Kind: UnexpectedColumn, Row: -1, Column: name})
+		}
+	}
+
+	nulls := nullMask(opts.Nulls)
+	if opts.Ordered {
+		differences = append(differences, compareOrdered(actual.Rows, expected[1:], nulls, columns, opts)...)
+	} else {
+		differences = append(differences, compareUnordered(actual.Rows, expected[1:], nulls, columns, opts)...)
+	}
+
+	return len(differences) == 0, differences
+}
+
+// nullMask marks the NULL cells of expected data rows that don't write NULL as
+// text. With a mask, the null marker takes no part in the comparison.
+type nullMask [][]bool
+
+func (m nullMask) isNull(i, j int) bool {
+	return i < len(m) && j < len(m[i]) && m[i][j]
+}
+
+// expectedNull reports whether the cell at position j of expected data row i
+// stands for NULL, by the null mask when there is one and its text otherwise
+func expectedNull(expected [][]string, nulls nullMask, i, j int, fieldType bigquery.FieldType, nullMarker string) bool {
+	if nulls != nil {
+		return nulls.isNull(i, j)
+	}
+	return isNull(cell(expected[i], j), fieldType, nullMarker)
+}
+
+// compareOrdered compares rows by position and reports per-cell differences
+func compareOrdered(actual [][]bigquery.Value, expected [][]string, nulls nullMask, columns []column, opts CompareOptions) []Difference {
+	if len(actual) != len(expected) {
+		return []Difference{{
+			Kind:     RowCountMismatch,
+			Row:      -1,
+			Expected: stringPtr(strconv.Itoa(len(expected))),
+			Actual:   stringPtr(strconv.Itoa(len(actual))),
+		}}
+	}
+
+	var differences []Difference
+	for i := range expected {
+		for _, col := range columns {
+			got := actual[i][col.actualPos]
+			if !cellEqual(got, expected, nulls, i, col, opts) {
+				diff := Difference{Kind: ValueMismatch, Row: i, Column: col.field.Name}
+				if !expectedNull(expected, nulls, i, col.expectedPos, col.field.Type, opts.NullMarker) {
+					diff.Expected = stringPtr(cell(expected[i], col.expectedPos))
+				}
+				if got != nil {
+					diff.Actual = stringPtr(formatValue(got))
+				}
+				differences = append(differences, diff)
+			}
+		}
+	}
+	return differences
+}
+
+// compareUnordered compares rows as multisets and reports missing and unexpected rows
+func compareUnordered(actual [][]bigquery.Value, expected [][]string, nulls nullMask, columns []column, opts CompareOptions) []Difference {
+	pairs, owners := matchRows(actual, expected, nulls, columns, opts)
+
+	var differences []Difference
+	for j, want := range expected {
+		if pairs[j] < 0 {
+			cells := make([]Cell, len(columns))
+			for k, col := range columns {
+				cells[k] = Cell{Column: col.field.Name}
+				if !expectedNull(expected, nulls, j, col.expectedPos, col.field.Type, opts.NullMarker) {
+					cells[k].Value = stringPtr(cell(want, col.expectedPos))
+				}
+			}
+			differences = append(differences, Difference{Kind: MissingRow, Row: j, Cells: cells})
+		}
+	}
+	for i, got := range actual {
+		if owners[i] >= 0 {
+			continue
+		}
+		cells := make([]Cell, len(columns))
+		for j, col := range columns {
+			cells[j] = Cell{Column: col.field.Name}
+			if v := got[col.actualPos]; v != nil {
+				cells[j].Value = stringPtr(formatValue(v))
+			}
+		}
+		differences = append(differences, Difference{Kind: UnexpectedRow, Row: i, Cells: cells})
+	}
+	return differences
+}
+
+// matchRows pairs expected rows with the actual rows they match, pairing as
+// many rows as possible. Within tolerances a row can match several others, so
+// rows are paired exactly first and then along augmenting paths. It returns the
+// actual row paired with every expected row and the expected row paired with
+// every actual row, or -1 for unpaired rows.
+func matchRows(actual [][]bigquery.Value, expected [][]string, nulls nullMask, columns []column, opts CompareOptions) ([]int, []int) {
+	pairs := make([]int, len(expected))
+	for j := range pairs {
+		pairs[j] = -1
+	}
+	owners := make([]int, len(actual))
+	for i := range owners {
+		owners[i] = -1
+	}
+
+	// Pair rows whose cells are exactly equal by their keys first
+	free := make(map[string][]int)
+	for i, got := range actual {
+		key := actualRowKey(got, columns)
+		free[key] = append(free[key], i)
+	}
+	for j := range expected {
+		key := expectedRowKey(expected, nulls, j, columns, opts)
+		if rows := free[key]; len(rows) > 0 {
+			pairs[j], owners[rows[0]] = rows[0], j
+			free[key] = rows[1:]
+		}
+	}
+
+	// Candidates are only built for the rows left over and the ones in their way
+	candidates := make([][]int, len(expected))
+	built := make([]bool, len(expected))
+	candidatesOf := func(j int) []int {
+		if !built[j] {
+			for i, got := range actual {
+				if rowsEqual(got, expected, nulls, j, columns, opts) {
+					candidates[j] = append(candidates[j], i)
+				}
+			}
+			built[j] = true
+		}
+		return candidates[j]
+	}
+
+	// augment pairs expected row j, re-pairing the rows in its way if needed
+	var augment func(j int, visited []bool) bool
+	augment = func(j int, visited []bool) bool {
+		for _, i := range candidatesOf(j) {
+			if visited[i] {
+				continue
+			}
+			visited[i] = true
+			if owners[i] < 0 || augment(owners[i], visited) {
+				pairs[j], owners[i] = i, j
+				return true
+			}
+		}
+		return false
+	}
+	for j := range expected {
+		if pairs[j] < 0 {
+			augment(j, make([]bool, len(actual)))
+		}
+	}
+	return pairs, owners
+}
+
+// actualRowKey builds a key of a result row's compared cells; rows with the
+// same key as an expected row are exactly equal to it
+func actualRowKey(row []bigquery.Value, columns []column) string {
+	var b strings.Builder
+	for _, col := range columns {
+		writeKeyPart(&b, actualCellKey(row[col.actualPos], col.field))
+	}
+	return b.String()
+}
+
+// expectedRowKey builds the key of expected data row i, as actualRowKey does
+func expectedRowKey(expected [][]string, nulls nullMask, i int, columns []column, opts CompareOptions) string {
+	var b strings.Builder
+	for _, col := range columns {
+		text := cell(expected[i], col.expectedPos)
+		null := text == opts.NullMarker
+		if nulls != nil {
+			null = nulls.isNull(i, col.expectedPos)
+		}
+		writeKeyPart(&b, expectedCellKey(text, null, col.field))
+	}
+	return b.String()
+}
+
+func writeKeyPart(b *strings.Builder, part string) {
+	fmt.Fprintf(b, "%d:%s", len(part), part)
+}
+
+// actualCellKey keys a result value by its typed value when it has its
+// column's type, and by its text otherwise
+func actualCellKey(value bigquery.Value, field *bigquery.FieldSchema) string {
+	if value == nil {
+		return "N"
+	}
+	if !field.Repeated {
+		if got, ok := typedValue(value, field.Type); ok {
+			return "V" + valueKey(got)
+		}
+	}
+	return "T" + formatValue(value)
+}
+
+// expectedCellKey keys an expected cell by its parsed value when it parses as
+// its column's type, and by its text otherwise
+func expectedCellKey(text string, null bool, field *bigquery.FieldSchema) string {
+	if null {
+		return "N"
+	}
+	if !field.Repeated {
+		if want, ok := parseExpected(text, field.Type); ok {
+			return "V" + valueKey(want)
+		}
+	}
+	return "T" + text
+}
+
+// valueKey formats a typed value so that equal keys mean exactly equal values
+func valueKey(value bigquery.Value) string {
+	switch v := value.(type) {
+	case *big.Rat:
+		return v.RatString()
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
+
+// formatRow renders the cells of a row as column='value' pairs, quoted like
+// the cells of value mismatches, with NULL values unquoted
+func formatRow(cells []Cell) string {
+	pairs := make([]string, len(cells))
+	for i, c := range cells {
+		pairs[i] = c.Column + "=" + quoteValue(c.Value)
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// quoteValue renders a value in single quotes, or NULL unquoted for nil
+func quoteValue(value *string) string {
+	if value == nil {
+		return "NULL"
+	}
+	return "'" + *value + "'"
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+// ExpectedValues returns the data rows of an expected output with nil for the
+// cells that stand for NULL, reading them as the comparison with results of
+// the given schema does. Columns missing from the schema are read as STRING.
+func ExpectedValues(expected [][]string, schema bigquery.Schema, opts CompareOptions) [][]*string {
+	if len(expected) == 0 {
+		return nil
+	}
+	types := make([]bigquery.FieldType, len(expected[0]))
+	for j, name := range expected[0] {
+		types[j] = bigquery.StringFieldType
+		for _, field := range schema {
+			if strings.EqualFold(field.Name, name) {
+				types[j] = field.Type
+			}
+		}
+	}
+
+	nulls := nullMask(opts.Nulls)
+	rows := make([][]*string, len(expected)-1)
+	for i, record := range expected[1:] {
+		rows[i] = make([]*string, len(record))
+		for j, value := range record {
+			fieldType := bigquery.StringFieldType
+			if j < len(types) {
+				fieldType = types[j]
+			}
+			if !expectedNull(expected[1:], nulls, i, j, fieldType, opts.NullMarker) {
+				rows[i][j] = stringPtr(value)
+			}
+		}
+	}
+	return rows
+}
+
+// rowsEqual reports whether a result row matches expected data row i
+func rowsEqual(actual []bigquery.Value, expected [][]string, nulls nullMask, i int, columns []column, opts CompareOptions) bool {
+	for _, col := range columns {
+		if !cellEqual(actual[col.actualPos], expected, nulls, i, col, opts) {
+			return false
+		}
+	}
+	return true
+}
+
+// cellEqual reports whether a result value matches the cell of column col in
+// expected data row i. With a null mask, NULL only matches a NULL cell.
+func cellEqual(actual bigquery.Value, expected [][]string, nulls nullMask, i int, col column, opts CompareOptions) bool {
+	if nulls != nil && (actual == nil || nulls.isNull(i, col.expectedPos)) {
+		return actual == nil && nulls.isNull(i, col.expectedPos)
+	}
+	return valuesEqual(actual, cell(expected[i], col.expectedPos), col.field, opts)
+}
+
+// valuesEqual reports whether a typed result value matches its expected text.
+// Values the expected text cannot be parsed as never match.
+func valuesEqual(actual bigquery.Value, expected string, field *bigquery.FieldSchema, opts CompareOptions) bool {
+	if actual == nil {
+		return expected == opts.NullMarker
+	}
+	if field.Repeated {
+		return formatValue(actual) == expected
+	}
+
+	got, ok := typedValue(actual, field.Type)
+	want, ok2 := parseExpected(expected, field.Type)
+	if ok && ok2 {
+		switch got := got.(type) {
+		case *big.Rat:
+			return got.Cmp(want.(*big.Rat)) == 0
+		case float64:
+			return floatsEqual(got, want.(float64), opts)
+		case time.Time:
+			return got.Equal(want.(time.Time))
+		}
+		return got == want
+	}
+
+	return formatValue(actual) == expected
+}
+
+// typedValue returns a result value when it has the Go type of its column's
+// type, with INTEGER values as rationals to compare with parsed decimals
+func typedValue(actual bigquery.Value, fieldType bigquery.FieldType) (bigquery.Value, bool) {
+	switch fieldType {
+	case bigquery.IntegerFieldType:
+		if got, ok := actual.(int64); ok {
+			return big.NewRat(got, 1), true
+		}
+		return nil, false
+	case bigquery.FloatFieldType:
+		_, ok := actual.(float64)
+		return actual, ok
+	case bigquery.NumericFieldType, bigquery.BigNumericFieldType:
+		_, ok := actual.(*big.Rat)
+		return actual, ok
+	case bigquery.BooleanFieldType:
+		_, ok := actual.(bool)
+		return actual, ok
+	case bigquery.TimestampFieldType:
+		_, ok := actual.(time.Time)
+		return actual, ok
+	case bigquery.DateTimeFieldType:
+		_, ok := actual.(civil.DateTime)
+		return actual, ok
+	case bigquery.DateFieldType:
+		_, ok := actual.(civil.Date)
+		return actual, ok
+	case bigquery.TimeFieldType:
+		_, ok := actual.(civil.Time)
+		return actual, ok
+	}
+	return nil, false
+}
+
+// parseExpected parses expected text as a value of its column's type, in the
+// Go type typedValue returns; other types are compared as text
+func parseExpected(expected string, fieldType bigquery.FieldType) (bigquery.Value, bool) {
+	var want bigquery.Value
+	var err error
+	switch fieldType {
+	case bigquery.IntegerFieldType, bigquery.NumericFieldType, bigquery.BigNumericFieldType:
+		rat, ok := parseDecimal(expected)
+		return rat, ok
+	case bigquery.FloatFieldType:
+		want, err = strconv.ParseFloat(expected, 64)
+	case bigquery.BooleanFieldType:
+		want, err = strconv.ParseBool(expected)
+	case bigquery.TimestampFieldType:
+		want, err = parseTimestamp(expected)
+	case bigquery.DateTimeFieldType:
+		want, err = civil.ParseDateTime(strings.Replace(expected, " ", "T", 1))
+	case bigquery.DateFieldType:
+		want, err = civil.ParseDate(expected)
+	case bigquery.TimeFieldType:
+		want, err = civil.ParseTime(expected)
+	default:
+		return nil, false
+	}
+	return want, err == nil
+}
+
+// floatsEqual compares two floats within the configured absolute or relative tolerance
+func floatsEqual(got, want float64, opts CompareOptions) bool {
+	if math.IsNaN(got) || math.IsNaN(want) {
+		return math.IsNaN(got) && math.IsNaN(want)
+	}
+	if got == want {
+		return true
+	}
+	if math.IsInf(got, 0) || math.IsInf(want, 0) {
+		return false
+	}
+	diff := math.Abs(got - want)
+	magnitude := math.Max(math.Abs(got), math.Abs(want))
+	// Decimals such as 0.95 aren't exact in binary, so differences on the
+	// tolerance boundary are allowed a few units of rounding error
+	slack := 4 * float64Epsilon * magnitude
+	if opts.AbsoluteTolerance > 0 && diff <= opts.AbsoluteTolerance+slack {
+		return true
+	}
+	return opts.RelativeTolerance > 0 && diff <= opts.RelativeTolerance*magnitude+slack
+}
+
+// float64Epsilon is the difference between 1 and the next larger float64
+const float64Epsilon = 0x1p-52
+
+// timestampLayouts are the accepted textual forms of expected TIMESTAMP values.
+// Zone abbreviations other than UTC are ambiguous and Go parses unknown ones as
+// UTC, so zones must be written as numeric offsets.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999 UTC",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// parseTimestamp parses an expected TIMESTAMP value. Values without a zone are UTC.
+func parseTimestamp(s string) (time.Time, error) {
+	var err error
+	for _, layout := range timestampLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// columnIndex maps lower-cased column names to their position in the header
+func columnIndex(header []string) map[string]int {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(name)] = i
+	}
+	return index
+}
+
+// cell returns the value at position i, or an empty string for short rows
+func cell(row []string, i int) string {
+	if i < len(row) {
+		return row[i]
+	}
+	return ""
+}
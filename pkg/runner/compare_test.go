@@ -0,0 +1,414 @@
+package runner
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/JoseTorrado/bqtest/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// stringResults builds STRING-typed results from a table whose first row is the header
+func stringResults(table [][]string) *Results {
+	results := &Results{}
+	for _, name := range table[0] {
+		results.Schema = append(results.Schema, &bigquery.FieldSchema{Name: name, Type: bigquery.StringFieldType})
+	}
+	for _, record := range table[1:] {
+		row := make([]bigquery.Value, len(record))
+		for i, v := range record {
+			row[i] = v
+		}
+		results.Rows = append(results.Rows, row)
+	}
+	return results
+}
+
+func TestCompareResults(t *testing.T) {
+	runner := &TestRunner{}
+
+	tests := []struct {
+		name      string
+		actual    [][]string
+		expected  [][]string
+		match     bool
+		diffCount int
+	}{
+		{
+			name:      "Exact match",
+			actual:    [][]string{{"a", "b"}, {"1", "2"}, {"3", "4"}},
+			expected:  [][]string{{"a", "b"}, {"1", "2"}, {"3", "4"}},
+			match:     true,
+			diffCount: 0,
+		},
+		{
+			name:      "Different values",
+			actual:    [][]string{{"a", "b"}, {"1", "2"}, {"3", "5"}},
+			expected:  [][]string{{"a", "b"}, {"1", "2"}, {"3", "4"}},
+			match:     false,
+			diffCount: 1,
+		},
+		{
+			name:      "Different row count",
+			actual:    [][]string{{"a", "b"}, {"1", "2"}},
+			expected:  [][]string{{"a", "b"}, {"1", "2"}, {"3", "4"}},
+			match:     false,
+			diffCount: 1,
+		},
+		{
+			name:      "Columns in different order",
+			actual:    [][]string{{"b", "a"}, {"2", "1"}, {"4", "3"}},
+			expected:  [][]string{{"a", "b"}, {"1", "2"}, {"3", "4"}},
+			match:     true,
+			diffCount: 0,
+		},
+		{
+			name:      "Column names differ in case",
+			actual:    [][]string{{"Country", "user_count"}, {"USA", "2"}},
+			expected:  [][]string{{"country", "user_count"}, {"USA", "2"}},
+			match:     true,
+			diffCount: 0,
+		},
+		{
+			name:      "Extra column",
+			actual:    [][]string{{"a", "b", "c"}, {"1", "2", "3"}, {"4", "5", "6"}},
+			expected:  [][]string{{"a", "b"}, {"1", "2"}, {"4", "5"}},
+			match:     false,
+			diffCount: 1,
+		},
+		{
+			name:      "Missing column",
+			actual:    [][]string{{"a"}, {"1"}, {"4"}},
+			expected:  [][]string{{"a", "b"}, {"1", "2"}, {"4", "5"}},
+			match:     false,
+			diffCount: 1,
+		},
+		{
+			name:      "Missing header",
+			actual:    [][]string{{"a", "b"}},
+			expected:  [][]string{},
+			match:     false,
+			diffCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, differences := runner.CompareResults(stringResults(tt.actual), tt.expected, CompareOptions{Ordered: true})
+			if match != tt.match {
+				t.Errorf("Expected match to be %v, got %v", tt.match, match)
+			}
+			if len(differences) != tt.diffCount {
+				t.Errorf("Expected %d differences, got %d: %v", tt.diffCount, len(differences), differences)
+			}
+		})
+	}
+}
+
+func TestCompareResultsUnordered(t *testing.T) {
+	runner := &TestRunner{}
+
+	tests := []struct {
+		name      string
+		actual    [][]string
+		expected  [][]string
+		match     bool
+		diffCount int
+	}{
+		{
+			name:      "Same rows in different order",
+			actual:    [][]string{{"a", "b"}, {"3", "4"}, {"1", "2"}},
+			expected:  [][]string{{"a", "b"}, {"1", "2"}, {"3", "4"}},
+			match:     true,
+			diffCount: 0,
+		},
+		{
+			name:      "Duplicate rows are counted",
+			actual:    [][]string{{"a"}, {"1"}, {"2"}},
+			expected:  [][]string{{"a"}, {"1"}, {"1"}},
+			match:     false,
+			diffCount: 2,
+		},
+		{
+			name:      "Missing row",
+			actual:    [][]string{{"a", "b"}, {"1", "2"}},
+			expected:  [][]string{{"a", "b"}, {"1", "2"}, {"3", "4"}},
+			match:     false,
+			diffCount: 1,
+		},
+		{
+			name:      "Unexpected row",
+			actual:    [][]string{{"a", "b"}, {"1", "2"}, {"5", "6"}, {"3", "4"}},
+			expected:  [][]string{{"a", "b"}, {"1", "2"}, {"3", "4"}},
+			match:     false,
+			diffCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, differences := runner.CompareResults(stringResults(tt.actual), tt.expected, CompareOptions{Ordered: false})
+			if match != tt.match {
+				t.Errorf("Expected match to be %v, got %v", tt.match, match)
+			}
+			if len(differences) != tt.diffCount {
+				t.Errorf("Expected %d differences, got %d: %v", tt.diffCount, len(differences), differences)
+			}
+		})
+	}
+}
+
+func TestCompareResultsUnorderedTolerance(t *testing.T) {
+	runner := &TestRunner{}
+
+	tests := []struct {
+		name     string
+		actual   []float64
+		expected []string
+		match    bool
+	}{
+		{"Greedy pairing would fail", []float64{1.04, 0.95}, []string{"1.0", "1.09"}, true},
+		{"Exact pair is re-paired", []float64{1.0, 0.96}, []string{"1.0", "1.04"}, true},
+		{"No pairing within tolerance", []float64{1.0, 1.2}, []string{"1.0", "1.04"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := &Results{Schema: bigquery.Schema{{Name: "value", Type: bigquery.FloatFieldType}}}
+			for _, v := range tt.actual {
+				actual.Rows = append(actual.Rows, []bigquery.Value{v})
+			}
+			expected := [][]string{{"value"}}
+			for _, v := range tt.expected {
+				expected = append(expected, []string{v})
+			}
+
+			match, differences := runner.CompareResults(actual, expected, CompareOptions{AbsoluteTolerance: 0.05})
+			if match != tt.match {
+				t.Errorf("Expected match to be %v, got %v: %v", tt.match, match, differences)
+			}
+		})
+	}
+}
+
+func TestCompareResultsTyped(t *testing.T) {
+	runner := &TestRunner{}
+
+	tests := []struct {
+		name     string
+		field    bigquery.FieldType
+		actual   bigquery.Value
+		expected string
+		opts     CompareOptions
+		match    bool
+	}{
+		{"Integer written as decimal", bigquery.IntegerFieldType, int64(2), "2.0", CompareOptions{}, true},
+		{"Integer written as non-integral decimal", bigquery.IntegerFieldType, int64(2), "2.5", CompareOptions{}, false},
+		{"Integer written as fraction", bigquery.IntegerFieldType, int64(2), "4/2", CompareOptions{}, false},
+		{"Different integers", bigquery.IntegerFieldType, int64(2), "3", CompareOptions{}, false},
+		{"Float without tolerance", bigquery.FloatFieldType, 0.30000000000000004, "0.3", CompareOptions{}, false},
+		{"Float within absolute tolerance", bigquery.FloatFieldType, 0.30000000000000004, "0.3", CompareOptions{AbsoluteTolerance: 1e-9}, true},
+		{"Float on the absolute tolerance boundary", bigquery.FloatFieldType, 0.95, "1.0", CompareOptions{AbsoluteTolerance: 0.05}, true},
+		{"Float within relative tolerance", bigquery.FloatFieldType, 1000.5, "1000", CompareOptions{RelativeTolerance: 0.001}, true},
+		{"Float outside relative tolerance", bigquery.FloatFieldType, 1002.0, "1000", CompareOptions{RelativeTolerance: 0.001}, false},
+		{"Numeric with different scale", bigquery.NumericFieldType, big.NewRat(3, 2), "1.50", CompareOptions{}, true},
+		{"Numeric written as fraction", bigquery.NumericFieldType, big.NewRat(1, 3), "1/3", CompareOptions{}, false},
+		{"Numeric is exact", bigquery.NumericFieldType, big.NewRat(1, 3), "0.333333333", CompareOptions{AbsoluteTolerance: 1}, false},
+		{"Timestamp in another zone", bigquery.TimestampFieldType, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), "2024-01-01T13:00:00+01:00", CompareOptions{}, true},
+		{"Timestamp in UTC", bigquery.TimestampFieldType, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), "2024-01-01 12:00:00 UTC", CompareOptions{}, true},
+		{"Timestamp with zone abbreviation", bigquery.TimestampFieldType, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), "2024-01-01 12:00:00 PST", CompareOptions{}, false},
+		{"Timestamp without zone", bigquery.TimestampFieldType, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), "2024-01-01 12:00:00", CompareOptions{}, true},
+		{"Boolean", bigquery.BooleanFieldType, true, "TRUE", CompareOptions{}, true},
+		{"Unparseable expected value", bigquery.IntegerFieldType, int64(2), "two", CompareOptions{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := &Results{
+				Schema: bigquery.Schema{{Name: "value", Type: tt.field}},
+				Rows:   [][]bigquery.Value{{tt.actual}},
+			}
+			expected := [][]string{{"value"}, {tt.expected}}
+			tt.opts.Ordered = true
+
+			match, differences := runner.CompareResults(actual, expected, tt.opts)
+			if match != tt.match {
+				t.Errorf("Expected match to be %v, got %v: %v", tt.match, match, differences)
+			}
+		})
+	}
+}
+
+func TestMatchRowsExactKeys(t *testing.T) {
+	columns := []column{
+		{field: &bigquery.FieldSchema{Name: "id", Type: bigquery.IntegerFieldType}, actualPos: 0, expectedPos: 0},
+		{field: &bigquery.FieldSchema{Name: "name", Type: bigquery.StringFieldType}, actualPos: 1, expectedPos: 1},
+		{field: &bigquery.FieldSchema{Name: "day", Type: bigquery.DateFieldType}, actualPos: 2, expectedPos: 2},
+	}
+	actual := [][]bigquery.Value{
+		{int64(1), "a", civil.Date{Year: 2024, Month: 1, Day: 1}},
+		{int64(2), "b", nil},
+		{int64(2), "b", nil},
+		{int64(3), "c", civil.Date{Year: 2024, Month: 1, Day: 3}},
+	}
+	expected := [][]string{
+		{"2.0", "b", "NULL"},
+		{"3", "c", "2024-01-03"},
+		{"2", "b", "NULL"},
+		{"1", "a", "2024-01-02"},
+	}
+
+	pairs, owners := matchRows(actual, expected, nil, columns, CompareOptions{NullMarker: "NULL"})
+	if want := []int{1, 3, 2, -1}; !reflect.DeepEqual(pairs, want) {
+		t.Errorf("Expected pairs %v, got %v", want, pairs)
+	}
+	if want := []int{-1, 0, 2, 1}; !reflect.DeepEqual(owners, want) {
+		t.Errorf("Expected owners %v, got %v", want, owners)
+	}
+}
+
+func TestDifferenceString(t *testing.T) {
+	tests := []struct {
+		diff     Difference
+		expected string
+	}{
+		{Difference{Kind: MissingColumn, Row: -1, Column: "a"}, "Missing column 'a'"},
+		{Difference{Kind: RowCountMismatch, Row: -1, Expected: stringPtr("2"), Actual: stringPtr("1")}, "Row count mismatch: expected 2, got 1"},
+		{Difference{Kind: ValueMismatch, Row: 3, Column: "a", Expected: stringPtr("1"), Actual: stringPtr("2")}, "Row 3, Column 'a': expected '1', got '2'"},
+		{Difference{Kind: ValueMismatch, Row: 3, Column: "a", Expected: stringPtr(""), Actual: nil}, "Row 3, Column 'a': expected '', got NULL"},
+		{Difference{Kind: MissingRow, Row: 0, Cells: []Cell{{"a", stringPtr("1")}, {"b", nil}}}, "Missing row: a='1', b=NULL"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.diff.String(); got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+	}
+}
+
+func TestCompareResultsNullMarker(t *testing.T) {
+	runner := &TestRunner{}
+	actual := &Results{
+		Schema: bigquery.Schema{{Name: "name", Type: bigquery.StringFieldType}},
+		Rows:   [][]bigquery.Value{{nil}, {""}},
+	}
+	opts := CompareOptions{Ordered: true, NullMarker: "NULL"}
+
+	if match, differences := runner.CompareResults(actual, [][]string{{"name"}, {"NULL"}, {""}}, opts); !match {
+		t.Errorf("Expected NULL and empty string to match, got %v", differences)
+	}
+	match, differences := runner.CompareResults(actual, [][]string{{"name"}, {""}, {"NULL"}}, opts)
+	if match {
+		t.Error("Expected NULL and empty string to be distinct")
+	}
+	expected := []Difference{
+		{Kind: ValueMismatch, Row: 0, Column: "name", Expected: stringPtr("")},
+		{Kind: ValueMismatch, Row: 1, Column: "name", Actual: stringPtr("")},
+	}
+	if !reflect.DeepEqual(differences, expected) {
+		t.Errorf("Expected NULL values to be nil, got %v", differences)
+	}
+
+	actual.NullMarker = "NULL"
+	if table := actual.Table(); table[1][0] != "NULL" || table[2][0] != "" {
+		t.Errorf("Expected NULL to render with the marker, got %v", table)
+	}
+}
+
+func TestExpectedValues(t *testing.T) {
+	schema := bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}, {Name: "name", Type: bigquery.StringFieldType}}
+	expected := [][]string{{"ID", "name"}, {"", ""}, {"1", "NULL"}}
+
+	values := ExpectedValues(expected, schema, CompareOptions{})
+	want := [][]*string{{nil, stringPtr("")}, {stringPtr("1"), stringPtr("NULL")}}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("Expected empty non-STRING cells to be NULL, got %v", values)
+	}
+
+	values = ExpectedValues(expected, schema, CompareOptions{Nulls: [][]bool{{false, true}, {false, false}}})
+	want = [][]*string{{stringPtr(""), nil}, {stringPtr("1"), stringPtr("NULL")}}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("Expected the null mask to mark NULL cells, got %v", values)
+	}
+}
+
+func TestCompareResultsNullMask(t *testing.T) {
+	runner := &TestRunner{}
+	actual := &Results{
+		Schema: bigquery.Schema{{Name: "name", Type: bigquery.StringFieldType}},
+		Rows:   [][]bigquery.Value{{nil}, {"NULL"}},
+	}
+	expected := [][]string{{"name"}, {"NULL"}, {"NULL"}}
+
+	for _, ordered := range []bool{true, false} {
+		opts := CompareOptions{Ordered: ordered, NullMarker: "NULL"}
+		opts.Nulls = [][]bool{{true}, {false}}
+		if match, differences := runner.CompareResults(actual, expected, opts); !match {
+			t.Errorf("Ordered %v: expected NULL and the string NULL to match, got %v", ordered, differences)
+		}
+		opts.Nulls = [][]bool{{true}, {true}}
+		if match, _ := runner.CompareResults(actual, expected, opts); match {
+			t.Errorf("Ordered %v: expected NULL and the string NULL to be distinct", ordered)
+		}
+	}
+}
+
+func TestCompareResultsInlineNulls(t *testing.T) {
+	runner := &TestRunner{}
+	var test models.Test
+	content := "name: inline\nexpected_rows:\n  - {name: null}\n"
+	if err := yaml.Unmarshal([]byte(content), &test); err != nil {
+		t.Fatalf("Failed to unmarshal test: %v", err)
+	}
+	expected, err := test.GetExpectedOutput()
+	if err != nil {
+		t.Fatalf("Failed to get expected output: %v", err)
+	}
+	opts := NewCompareOptions(&test)
+
+	for _, tt := range []struct {
+		value bigquery.Value
+		match bool
+	}{{nil, true}, {"", false}} {
+		actual := &Results{
+			Schema: bigquery.Schema{{Name: "name", Type: bigquery.StringFieldType}},
+			Rows:   [][]bigquery.Value{{tt.value}},
+		}
+		if match, differences := runner.CompareResults(actual, expected, opts); match != tt.match {
+			t.Errorf("Expected %#v to match an inline null: %v, got %v: %v", tt.value, tt.match, match, differences)
+		}
+	}
+}
+
+func TestCompareResultsRowFormat(t *testing.T) {
+	runner := &TestRunner{}
+	actual := &Results{
+		Schema: bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}, {Name: "name", Type: bigquery.StringFieldType}},
+		Rows:   [][]bigquery.Value{{int64(2), nil}},
+	}
+	opts := CompareOptions{NullMarker: "NULL"}
+
+	_, differences := runner.CompareResults(actual, [][]string{{"id", "name"}, {"1", "foo"}}, opts)
+	expected := []Difference{
+		{Kind: MissingRow, Row: 0, Cells: []Cell{{"id", stringPtr("1")}, {"name", stringPtr("foo")}}},
+		{Kind: UnexpectedRow, Row: 0, Cells: []Cell{{"id", stringPtr("2")}, {"name", nil}}},
+	}
+	if !reflect.DeepEqual(differences, expected) {
+		t.Errorf("Expected %v, got %v", expected, differences)
+	}
+
+	// With a null marker, an empty INTEGER cell isn't NULL and doesn't match one
+	actual.Rows = [][]bigquery.Value{{nil, nil}}
+	_, differences = runner.CompareResults(actual, [][]string{{"id", "name"}, {"", "NULL"}}, opts)
+	expected = []Difference{
+		{Kind: MissingRow, Row: 0, Cells: []Cell{{"id", stringPtr("")}, {"name", nil}}},
+		{Kind: UnexpectedRow, Row: 0, Cells: []Cell{{"id", nil}, {"name", nil}}},
+	}
+	if !reflect.DeepEqual(differences, expected) {
+		t.Errorf("Expected %v, got %v", expected, differences)
+	}
+}
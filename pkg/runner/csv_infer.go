@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// Plain decimal numbers, without leading zeros that identifiers such as zip
+// codes rely on, exponents, hexadecimal digits, NaN or Inf
+var (
+	csvIntegerPattern = regexp.MustCompile(`^[+-]?(0|[1-9][0-9]*)$`)
+	csvFloatPattern   = regexp.MustCompile(`^[+-]?(0|[1-9][0-9]*)?\.[0-9]+$|^[+-]?(0|[1-9][0-9]*)\.$`)
+)
+
+// InferCSVSchema infers the schema of CSV records, header first, from all of
+// their rows. A column is INT64, FLOAT64, BOOL, DATE or TIMESTAMP when all of
+// its values are, with integers widening to floats; any other column is a
+// STRING. NULL cells, those equal to nullMarker or empty without a marker,
+// are ignored.
+func InferCSVSchema(records [][]string, nullMarker string) (bigquery.Schema, error) {
+	if len(records) == 0 {
+		return nil, errors.New("CSV file must contain a header row")
+	}
+	if err := checkCSVHeaders(records[0]); err != nil {
+		return nil, err
+	}
+
+	schema := bigquery.Schema{}
+	for i, header := range records[0] {
+		schema = append(schema, &bigquery.FieldSchema{
+			Name: header,
+			Type: inferCSVColumn(records[1:], i, nullMarker),
+		})
+	}
+	return schema, nil
+}
+
+// inferCSVColumn returns the type shared by the values of column i
+func inferCSVColumn(rows [][]string, i int, nullMarker string) bigquery.FieldType {
+	var fieldType bigquery.FieldType
+	for _, row := range rows {
+		value := cell(row, i)
+		// Without a marker, nullMarker is empty like the NULL cells
+		if value == nullMarker {
+			continue
+		}
+
+		t := inferCSVType(value)
+		switch {
+		case fieldType == "" || fieldType == t:
+			fieldType = t
+		case isNumeric(fieldType) && isNumeric(t):
+			// Integers widen to floats
+			fieldType = bigquery.FloatFieldType
+		default:
+			return bigquery.StringFieldType
+		}
+	}
+	if fieldType == "" {
+		return bigquery.StringFieldType
+	}
+	return fieldType
+}
+
+// inferCSVType returns the narrowest type that can hold a single CSV value and
+// load it back unchanged
+func inferCSVType(value string) bigquery.FieldType {
+	if csvIntegerPattern.MatchString(value) {
+		if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return bigquery.IntegerFieldType
+		}
+	}
+	if csvFloatPattern.MatchString(value) {
+		return bigquery.FloatFieldType
+	}
+	switch value {
+	// The spellings strconv.ParseBool accepts when the column is loaded
+	case "true", "True", "TRUE", "false", "False", "FALSE":
+		return bigquery.BooleanFieldType
+	}
+	if _, err := time.Parse("2006-01-02", value); err == nil {
+		return bigquery.DateFieldType
+	}
+	if _, err := parseTimestamp(value); err == nil {
+		return bigquery.TimestampFieldType
+	}
+	return bigquery.StringFieldType
+}
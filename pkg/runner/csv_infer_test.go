@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"encoding/json"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+)
+
+func TestInferCSVSchema(t *testing.T) {
+	records := [][]string{
+		{"id", "score", "active", "signup_date", "updated_at", "name", "notes", "mixed"},
+		{"1", "1.5", "true", "2024-01-01", "2024-01-01T10:00:00Z", "Alice", "", "1"},
+		{"2", "2", "FALSE", "2024-02-29", "2024-01-02 10:00:00", "Bob", `\N`, "2024-01-01"},
+		{`\N`, `\N`, `\N`, `\N`, `\N`, "3", "", "true"},
+	}
+
+	schema, err := InferCSVSchema(records, `\N`)
+	if err != nil {
+		t.Fatalf("Failed to infer schema: %v", err)
+	}
+
+	expected := []bigquery.FieldType{
+		bigquery.IntegerFieldType,
+		bigquery.FloatFieldType,
+		bigquery.BooleanFieldType,
+		bigquery.DateFieldType,
+		bigquery.TimestampFieldType,
+		bigquery.StringFieldType,
+		bigquery.StringFieldType,
+		bigquery.StringFieldType,
+	}
+	if len(schema) != len(expected) {
+		t.Fatalf("Expected %d fields, got %d", len(expected), len(schema))
+	}
+	for i, fieldType := range expected {
+		if schema[i].Type != fieldType {
+			t.Errorf("Expected column '%s' to be %s, got %s", records[0][i], fieldType, schema[i].Type)
+		}
+	}
+}
+
+func TestInferCSVSchemaEmptyCells(t *testing.T) {
+	records := [][]string{{"id"}, {"1"}, {""}}
+
+	// Empty cells are NULL only without a null marker
+	for marker, expected := range map[string]bigquery.FieldType{"": bigquery.IntegerFieldType, "NULL": bigquery.StringFieldType} {
+		schema, err := InferCSVSchema(records, marker)
+		if err != nil {
+			t.Fatalf("Failed to infer schema: %v", err)
+		}
+		if schema[0].Type != expected {
+			t.Errorf("Null marker %q: expected %s, got %s", marker, expected, schema[0].Type)
+		}
+	}
+}
+
+func TestInferCSVSchemaJSON(t *testing.T) {
+	records := [][]string{{"id", "score", "active", "name"}, {"1", "1.5", "true", "Alice"}}
+	schema, err := InferCSVSchema(records, "")
+	if err != nil {
+		t.Fatalf("Failed to infer schema: %v", err)
+	}
+
+	out, err := SchemaJSON(schema)
+	if err != nil {
+		t.Fatalf("Failed to encode schema: %v", err)
+	}
+	var fields []struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(out, &fields); err != nil {
+		t.Fatalf("Failed to decode schema: %v", err)
+	}
+	expected := []string{"INT64", "FLOAT64", "BOOL", "STRING"}
+	for i, fieldType := range expected {
+		if fields[i].Type != fieldType {
+			t.Errorf("Expected column '%s' to be written as %s, got %s", records[0][i], fieldType, fields[i].Type)
+		}
+	}
+	if schema[0].Type != bigquery.IntegerFieldType {
+		t.Errorf("Expected the inferred schema to be left untouched, got %s", schema[0].Type)
+	}
+}
+
+func TestInferCSVSchemaAllRows(t *testing.T) {
+	records := [][]string{{"id"}}
+	for i := 0; i < 2000; i++ {
+		records = append(records, []string{"1"})
+	}
+	records = append(records, []string{"not a number"})
+
+	schema, err := InferCSVSchema(records, "")
+	if err != nil {
+		t.Fatalf("Failed to infer schema: %v", err)
+	}
+	if schema[0].Type != bigquery.StringFieldType {
+		t.Errorf("Expected the last row to make the column a STRING, got %s", schema[0].Type)
+	}
+}
+
+func TestInferCSVType(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected bigquery.FieldType
+	}{
+		{"0", bigquery.IntegerFieldType},
+		{"-42", bigquery.IntegerFieldType},
+		{"00501", bigquery.StringFieldType},
+		{"99999999999999999999", bigquery.StringFieldType},
+		{"1.5", bigquery.FloatFieldType},
+		{"-0.25", bigquery.FloatFieldType},
+		{"007.5", bigquery.StringFieldType},
+		{"1e5", bigquery.StringFieldType},
+		{"0x1p-2", bigquery.StringFieldType},
+		{"NaN", bigquery.StringFieldType},
+		{"Inf", bigquery.StringFieldType},
+		{"True", bigquery.BooleanFieldType},
+		{"tRuE", bigquery.StringFieldType},
+		{"2024-01-01", bigquery.DateFieldType},
+	}
+
+	for _, tt := range tests {
+		if got := inferCSVType(tt.value); got != tt.expected {
+			t.Errorf("Expected '%s' to be %s, got %s", tt.value, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/JoseTorrado/bqtest/pkg/models"
+)
+
+// fixtureNullMarker is the text NULL values are shown as in fixture results and
+// differences. Fixture comparisons tell NULL apart by value, never by this text.
+const fixtureNullMarker = "NULL"
+
+// Fixture defines a test in Go rather than in a config file: tables with a typed
+// schema and rows, and queries run against them.
+//
+//	users := runner.Table("users").
+//		Column("id", runner.INT64).
+//		Column("name", runner.STRING).
+//		Row(1, "Alice").
+//		Row(2, nil)
+//	f := testRunner.Fixture().Add(users)
+//	passed, diffs, err := f.Query("SELECT name FROM ${users} WHERE id = 1").
+//		Columns("name").
+//		Row("Alice").
+//		Check()
+//
+// Like config tests, every fixture loads its tables into its own dataset, and
+// queries refer to them with ${table_name} placeholders.
+type Fixture struct {
+	runner  *TestRunner
+	dataset string // isolated dataset the tables are loaded into
+	tables  []*TableBuilder
+}
+
+// Fixture creates an empty fixture whose tables are loaded into the emulator
+func (r *TestRunner) Fixture() *Fixture {
+	return &Fixture{runner: r, dataset: r.newDatasetID()}
+}
+
+// TableBuilder declares the columns and rows of a fixture table
+type TableBuilder struct {
+	name   string
+	schema bigquery.Schema
+	rows   [][]bigquery.Value
+	err    error // first declaration error, reported when the fixture runs
+}
+
+// Table starts the declaration of a table on its own, to be added to fixtures
+// with Fixture.Add
+func Table(name string) *TableBuilder {
+	return &TableBuilder{name: name}
+}
+
+// Table adds a table to the fixture, or returns it if it was already added
+func (f *Fixture) Table(name string) *TableBuilder {
+	for _, table := range f.tables {
+		if table.name == name {
+			return table
+		}
+	}
+	table := Table(name)
+	f.tables = append(f.tables, table)
+	return table
+}
+
+// Add adds tables declared with Table to the fixture. A table can be added to
+// several fixtures, but not twice to the same one.
+func (f *Fixture) Add(tables ...*TableBuilder) *Fixture {
+	f.tables = append(f.tables, tables...)
+	return f
+}
+
+// Column adds a column to the table. Columns must be declared before rows.
+func (tb *TableBuilder) Column(name string, fieldType bigquery.FieldType) *TableBuilder {
+	switch {
+	case tb.err != nil:
+	case len(tb.rows) > 0:
+		tb.err = fmt.Errorf("column '%s' declared after rows", name)
+	default:
+		tb.err = models.ValidateFieldName(name)
+	}
+	tb.schema = append(tb.schema, &bigquery.FieldSchema{Name: name, Type: fieldType})
+	return tb
+}
+
+// Row adds a row with one value per column; nil is NULL. Values are Go values
+// of the column's type, such as int64, float64, civil.Date or time.Time, or
+// strings in the same text form as CSV cells.
+func (tb *TableBuilder) Row(values ...bigquery.Value) *TableBuilder {
+	if tb.err != nil {
+		return tb
+	}
+	if len(values) != len(tb.schema) {
+		tb.err = fmt.Errorf("row %d has %d values, expected %d", len(tb.rows), len(values), len(tb.schema))
+		return tb
+	}
+
+	row := make([]bigquery.Value, len(values))
+	for i, value := range values {
+		field := tb.schema[i]
+		converted, err := fixtureValue(value, field.Type)
+		if err != nil {
+			tb.err = fmt.Errorf("row %d, column '%s': %v", len(tb.rows), field.Name, err)
+			return tb
+		}
+		row[i] = converted
+	}
+	tb.rows = append(tb.rows, row)
+	return tb
+}
+
+// fixtureValue parses string values according to the column type and checks
+// that other values are of a Go type the client uploads as that column type
+func fixtureValue(value bigquery.Value, fieldType bigquery.FieldType) (bigquery.Value, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if s, ok := value.(string); ok {
+		return convertValue(s, fieldType)
+	}
+
+	ok := true
+	switch fieldType {
+	case bigquery.IntegerFieldType:
+		ok = isGoInt(value)
+	case bigquery.FloatFieldType:
+		switch value.(type) {
+		case float32, float64:
+		default:
+			ok = isGoInt(value)
+		}
+	case bigquery.BooleanFieldType:
+		_, ok = value.(bool)
+	case bigquery.BytesFieldType:
+		_, ok = value.([]byte)
+	case bigquery.NumericFieldType, bigquery.BigNumericFieldType:
+		_, ok = value.(*big.Rat)
+	case bigquery.DateFieldType:
+		_, ok = value.(civil.Date)
+	case bigquery.TimestampFieldType:
+		_, ok = value.(time.Time)
+	case bigquery.TimeFieldType:
+		_, ok = value.(civil.Time)
+	case bigquery.DateTimeFieldType:
+		_, ok = value.(civil.DateTime)
+	case bigquery.IntervalFieldType:
+		_, ok = value.(*bigquery.IntervalValue)
+	case bigquery.StringFieldType, bigquery.GeographyFieldType, bigquery.JSONFieldType:
+		// Only strings, handled above
+		ok = false
+	}
+	if !ok {
+		return nil, fmt.Errorf("unsupported %T value for a %s column", value, fieldType)
+	}
+	return value, nil
+}
+
+// isGoInt reports whether value is of one of Go's integer types
+func isGoInt(value bigquery.Value) bool {
+	switch value.(type) {
+	case int, int8, int16, int32, int64, uint8, uint16, uint32:
+		return true
+	}
+	return false
+}
+
+// Run loads the fixture's tables, runs the query and returns its results.
+// The tables are dropped afterwards.
+func (f *Fixture) Run(query string) (*Results, error) {
+	ctx := context.Background()
+	datasetID := f.dataset
+	dataset := f.runner.Client.Dataset(datasetID)
+
+	if err := f.runner.ensureDatasetExists(ctx, dataset); err != nil {
+		return nil, err
+	}
+	defer f.runner.dropDataset(ctx, datasetID)
+
+	refs := make(map[string]string)
+	for _, table := range f.tables {
+		if table.err != nil {
+			return nil, fmt.Errorf("table '%s': %v", table.name, table.err)
+		}
+		if len(table.schema) == 0 {
+			return nil, fmt.Errorf("table '%s': no columns declared", table.name)
+		}
+		if _, ok := refs[table.name]; ok {
+			return nil, fmt.Errorf("table '%s': added more than once", table.name)
+		}
+		if err := f.runner.createTable(ctx, dataset, table.name, table.schema, table.rows); err != nil {
+			return nil, fmt.Errorf("table '%s': %v", table.name, err)
+		}
+		refs[table.name] = fmt.Sprintf("`%s.%s`", datasetID, table.name)
+	}
+
+	query, err := renderVars(query, refs, nil)
+	if err != nil {
+		return nil, err
+	}
+	return f.runner.runQuery(ctx, f.runner.Client, query, nil, fixtureNullMarker)
+}
+
+// Expectation holds the typed rows a fixture query is expected to return
+type Expectation struct {
+	fixture *Fixture
+	query   string
+	columns []string
+	rows    [][]bigquery.Value
+	opts    CompareOptions
+}
+
+// Query starts an expectation on the results of query. Rows are expected in
+// order unless Unordered is called.
+func (f *Fixture) Query(query string) *Expectation {
+	return &Expectation{
+		fixture: f,
+		query:   query,
+		opts:    CompareOptions{Ordered: true, NullMarker: fixtureNullMarker},
+	}
+}
+
+// Columns sets the expected column names
+func (e *Expectation) Columns(names ...string) *Expectation {
+	e.columns = append(e.columns, names...)
+	return e
+}
+
+// Row adds an expected row with one value per column; nil is NULL
+func (e *Expectation) Row(values ...bigquery.Value) *Expectation {
+	e.rows = append(e.rows, values)
+	return e
+}
+
+// Unordered compares the rows as multisets
+func (e *Expectation) Unordered() *Expectation {
+	e.opts.Ordered = false
+	return e
+}
+
+// Tolerance bounds the allowed difference between FLOAT values
+func (e *Expectation) Tolerance(absolute, relative float64) *Expectation {
+	e.opts.AbsoluteTolerance = absolute
+	e.opts.RelativeTolerance = relative
+	return e
+}
+
+// Check runs the query and compares its results with the expected rows.
+// Expected values are compared by their text form, parsed according to the
+// type of the matching result column. Expected nil values only match NULL.
+func (e *Expectation) Check() (bool, []Difference, error) {
+	if len(e.columns) == 0 {
+		return false, nil, errors.New("no expected columns declared")
+	}
+
+	expected := [][]string{e.columns}
+	nulls := make([][]bool, 0, len(e.rows))
+	for i, row := range e.rows {
+		if len(row) != len(e.columns) {
+			return false, nil, fmt.Errorf("expected row %d has %d values, expected %d", i, len(row), len(e.columns))
+		}
+		record := make([]string, len(row))
+		rowNulls := make([]bool, len(row))
+		for j, v := range row {
+			record[j] = formatCell(v, fixtureNullMarker)
+			rowNulls[j] = v == nil
+		}
+		expected = append(expected, record)
+		nulls = append(nulls, rowNulls)
+	}
+
+	actual, err := e.fixture.Run(e.query)
+	if err != nil {
+		return false, nil, err
+	}
+
+	opts := e.opts
+	opts.Nulls = nulls
+	passed, differences := e.fixture.runner.CompareResults(actual, expected, opts)
+	return passed, differences, nil
+}
@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+)
+
+func TestTableBuilder(t *testing.T) {
+	t.Run("Typed and text values", func(t *testing.T) {
+		f := &Fixture{}
+		table := f.Table("users").
+			Column("id", INT64).
+			Column("amount", NUMERIC).
+			Column("signup", DATE).
+			Row(1, "12.50", civil.Date{Year: 2024, Month: 1, Day: 2}).
+			Row(int64(2), big.NewRat(3, 2), nil)
+		if table.err != nil {
+			t.Fatalf("Unexpected error: %v", table.err)
+		}
+		if f.Table("users") != table {
+			t.Error("Expected Table to return the existing table")
+		}
+
+		expected := [][]bigquery.Value{
+			{1, big.NewRat(25, 2), civil.Date{Year: 2024, Month: 1, Day: 2}},
+			{int64(2), big.NewRat(3, 2), nil},
+		}
+		if !reflect.DeepEqual(table.rows, expected) {
+			t.Errorf("Expected rows %v, got %v", expected, table.rows)
+		}
+	})
+
+	t.Run("Standalone table", func(t *testing.T) {
+		users := Table("users").Column("id", INT64).Row(1)
+		f := (&Fixture{}).Add(users)
+		if f.Table("users") != users {
+			t.Error("Expected the added table to belong to the fixture")
+		}
+		if users.err != nil {
+			t.Errorf("Unexpected error: %v", users.err)
+		}
+	})
+
+	t.Run("Go values of each type", func(t *testing.T) {
+		table := (&Fixture{}).Table("events").
+			Column("id", INT64).
+			Column("score", FLOAT64).
+			Column("active", BOOL).
+			Column("payload", BYTES).
+			Column("created_at", TIMESTAMP).
+			Row(int32(1), 2.5, true, []byte("a"), time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)).
+			Row(int64(2), 3, false, nil, nil)
+		if table.err != nil {
+			t.Errorf("Unexpected error: %v", table.err)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		build func(*TableBuilder)
+	}{
+		{"Invalid column name", func(tb *TableBuilder) { tb.Column("first name", STRING) }},
+		{"Column after rows", func(tb *TableBuilder) { tb.Column("id", INT64).Row(1).Column("name", STRING) }},
+		{"Too few values", func(tb *TableBuilder) { tb.Column("id", INT64).Column("name", STRING).Row(1) }},
+		{"Unparseable text", func(tb *TableBuilder) { tb.Column("id", INT64).Row("one") }},
+		{"Unsupported Go type", func(tb *TableBuilder) { tb.Column("amount", NUMERIC).Row(12.5) }},
+		{"Bool in an INT64 column", func(tb *TableBuilder) { tb.Column("id", INT64).Row(true) }},
+		{"Float in an INT64 column", func(tb *TableBuilder) { tb.Column("id", INT64).Row(1.5) }},
+		{"Int in a BOOL column", func(tb *TableBuilder) { tb.Column("active", BOOL).Row(1) }},
+		{"Int in a STRING column", func(tb *TableBuilder) { tb.Column("name", STRING).Row(1) }},
+		{"Unparseable date", func(tb *TableBuilder) { tb.Column("signup", DATE).Row("x") }},
+		{"Time in a DATE column", func(tb *TableBuilder) { tb.Column("signup", DATE).Row(time.Now()) }},
+		{"Date in a TIMESTAMP column", func(tb *TableBuilder) {
+			tb.Column("updated_at", TIMESTAMP).Row(civil.Date{Year: 2024, Month: 1, Day: 2})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := (&Fixture{}).Table("users")
+			tt.build(table)
+			if table.err == nil {
+				t.Error("Expected an error, got none")
+			}
+		})
+	}
+
+	t.Run("Error names the row and column", func(t *testing.T) {
+		table := (&Fixture{}).Table("users").
+			Column("id", INT64).
+			Column("active", BOOL).
+			Row(1, false).
+			Row(2, "yes")
+		if table.err == nil || !strings.Contains(table.err.Error(), "row 1, column 'active'") {
+			t.Errorf("Expected an error on row 1, column 'active', got %v", table.err)
+		}
+	})
+}
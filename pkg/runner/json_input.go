@@ -0,0 +1,267 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/JoseTorrado/bqtest/pkg/fileutil"
+	"github.com/JoseTorrado/bqtest/pkg/models"
+)
+
+// readJSONInput reads a JSON or NDJSON input file. Unless the input has a schema
+// file, the schema, including REPEATED and RECORD fields, is inferred from the
+// objects in the file.
+func readJSONInput(input *models.Input) (bigquery.Schema, [][]bigquery.Value, error) {
+	records, err := fileutil.ReadJSONFile(input.File)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read input JSON: %v", err)
+	}
+
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("JSON file must contain at least one row")
+	}
+
+	var schema bigquery.Schema
+	if input.Schema != "" {
+		schema, err = readSchemaFile(input.Schema)
+	} else {
+		schema, err = inferJSONSchema(records, "", input.SchemaOverrides)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows := make([][]bigquery.Value, len(records))
+	for i, record := range records {
+		rows[i], err = convertJSONRecord(record, schema)
+		if err != nil {
+			return nil, nil, fmt.Errorf("row %d: %v", i, err)
+		}
+	}
+
+	return schema, rows, nil
+}
+
+// inferJSONSchema derives the schema of a set of JSON objects. Overrides, keyed by dotted field path (e.g. "address.zip"),
+// replace the inferred type of fields. Objects can be overridden as JSON or STRING fields, which hold their JSON text.
+func inferJSONSchema(records []map[string]interface{}, prefix string, overrides map[string]string) (bigquery.Schema, error) {
+	// Map iteration order is random, so fields are ordered by the first record
+	// that has them, and alphabetically within a record
+	names := orderFields(records)
+
+	schema := bigquery.Schema{}
+	for _, name := range names {
+		path := prefix + name
+		if err := models.ValidateFieldName(name); err != nil {
+			return nil, fmt.Errorf("field '%s': %v", path, err)
+		}
+		field := &bigquery.FieldSchema{Name: name, Type: bigquery.StringFieldType}
+
+		var overrideType bigquery.FieldType
+		if override, ok := overrides[path]; ok {
+			var err error
+			if overrideType, err = models.ParseFieldType(override); err != nil {
+				return nil, fmt.Errorf("schema override for '%s': %v", path, err)
+			}
+		}
+		if overrideType == bigquery.JSONFieldType {
+			// JSON fields hold each value whole, objects and arrays included
+			schema = append(schema, &bigquery.FieldSchema{Name: name, Type: overrideType})
+			continue
+		}
+
+		var elements []interface{}
+		isArray, isScalar := false, false
+		for _, record := range records {
+			switch v := record[name].(type) {
+			case nil:
+			case []interface{}:
+				isArray = true
+				elements = append(elements, v...)
+			default:
+				isScalar = true
+				elements = append(elements, v)
+			}
+		}
+		if isArray && isScalar {
+			return nil, fmt.Errorf("field '%s' mixes arrays and single values", path)
+		}
+		field.Repeated = isArray
+
+		fieldType, nested, err := inferJSONType(elements, path)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case overrideType == bigquery.RecordFieldType && fieldType != bigquery.RecordFieldType:
+			return nil, fmt.Errorf("schema override for '%s': %s values cannot be loaded into a RECORD field", path, fieldType)
+		case fieldType == bigquery.RecordFieldType && overrideType != "" && overrideType != bigquery.RecordFieldType && overrideType != bigquery.StringFieldType:
+			return nil, fmt.Errorf("schema override for '%s': objects can only be loaded into RECORD, JSON or STRING fields", path)
+		case overrideType != "" && overrideType != bigquery.RecordFieldType:
+			field.Type = overrideType
+		default:
+			field.Type = fieldType
+		}
+		if field.Type == bigquery.RecordFieldType {
+			field.Schema, err = inferJSONSchema(nested, path+".", overrides)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		schema = append(schema, field)
+	}
+	return schema, nil
+}
+
+// inferJSONType returns the BigQuery type shared by all values of a field. For
+// RECORD fields the nested objects are returned for further inference.
+func inferJSONType(values []interface{}, path string) (bigquery.FieldType, []map[string]interface{}, error) {
+	var fieldType bigquery.FieldType
+	var nested []map[string]interface{}
+	for _, value := range values {
+		var t bigquery.FieldType
+		switch v := value.(type) {
+		case nil:
+			continue
+		case map[string]interface{}:
+			t = bigquery.RecordFieldType
+			nested = append(nested, v)
+		case json.Number:
+			t = bigquery.IntegerFieldType
+			if _, err := v.Int64(); err != nil {
+				t = bigquery.FloatFieldType
+			}
+		case bool:
+			t = bigquery.BooleanFieldType
+		case string:
+			t = bigquery.StringFieldType
+		default:
+			return "", nil, fmt.Errorf("field '%s' has unsupported value %v", path, v)
+		}
+
+		switch {
+		case fieldType == "" || fieldType == t:
+			fieldType = t
+		case isNumeric(fieldType) && isNumeric(t):
+			// Integers widen to floats
+			fieldType = bigquery.FloatFieldType
+		default:
+			return "", nil, fmt.Errorf("field '%s' mixes %s and %s values", path, fieldType, t)
+		}
+	}
+	if fieldType == "" {
+		fieldType = bigquery.StringFieldType
+	}
+	return fieldType, nested, nil
+}
+
+func isNumeric(t bigquery.FieldType) bool {
+	return t == bigquery.IntegerFieldType || t == bigquery.FloatFieldType
+}
+
+// orderFields returns every field name, ordered by the first record that contains it
+func orderFields(records []map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var ordered []string
+	for _, record := range records {
+		var keys []string
+		for name := range record {
+			if !seen[name] {
+				keys = append(keys, name)
+			}
+		}
+		sort.Strings(keys)
+		for _, name := range keys {
+			seen[name] = true
+			ordered = append(ordered, name)
+		}
+	}
+	return ordered
+}
+
+// convertJSONRecord converts a JSON object into row values matching the schema.
+// Nested records become []bigquery.Value, as expected by bigquery.ValuesSaver.
+func convertJSONRecord(record map[string]interface{}, schema bigquery.Schema) ([]bigquery.Value, error) {
+	if err := checkJSONFields(record, schema); err != nil {
+		return nil, err
+	}
+
+	row := make([]bigquery.Value, len(schema))
+	for i, field := range schema {
+		value, err := convertJSONValue(record[field.Name], field)
+		if err != nil {
+			return nil, fmt.Errorf("field '%s': %v", field.Name, err)
+		}
+		row[i] = value
+	}
+	return row, nil
+}
+
+func convertJSONValue(value interface{}, field *bigquery.FieldSchema) (bigquery.Value, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	if field.Repeated {
+		elements, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an array, got %v", value)
+		}
+		element := *field
+		element.Repeated = false
+		values := make([]bigquery.Value, len(elements))
+		for i, v := range elements {
+			if v == nil {
+				return nil, fmt.Errorf("array element %d is null, which REPEATED fields cannot hold", i)
+			}
+			converted, err := convertJSONValue(v, &element)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = converted
+		}
+		return values, nil
+	}
+
+	if field.Type == bigquery.JSONFieldType {
+		// Strings are JSON string values too, not JSON text
+		text, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return string(text), nil
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		switch field.Type {
+		case bigquery.RecordFieldType:
+			return convertJSONRecord(v, field.Schema)
+		case bigquery.StringFieldType:
+			text, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			return string(text), nil
+		default:
+			return nil, fmt.Errorf("unexpected object for %s field", field.Type)
+		}
+	case bool:
+		if field.Type == bigquery.StringFieldType {
+			return fmt.Sprintf("%v", v), nil
+		}
+		if field.Type != bigquery.BooleanFieldType {
+			return nil, fmt.Errorf("unexpected boolean for %s field", field.Type)
+		}
+		return v, nil
+	case json.Number:
+		return convertValue(v.String(), field.Type)
+	case string:
+		return convertValue(v, field.Type)
+	default:
+		return nil, fmt.Errorf("unsupported value %v", v)
+	}
+}
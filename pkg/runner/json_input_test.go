@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/JoseTorrado/bqtest/pkg/models"
+)
+
+func TestReadJSONInput(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "jsoninput")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	content := `{"id": 1, "name": "foo", "tags": ["a", "b"], "address": {"city": "Paris", "since": "2020-01-01"}, "events": [{"kind": "click", "count": 2}]}
+{"id": 2, "name": null, "tags": [], "address": {"city": "Lyon", "since": "2021-06-01"}, "events": [{"kind": "view", "count": 2.5}], "score": 1.5}
+`
+	path := filepath.Join(tmpDir, "users.ndjson")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	input := &models.Input{
+		TableName:       "users",
+		File:            path,
+		SchemaOverrides: map[string]string{"address.since": "DATE"},
+	}
+
+	schema, rows, err := readJSONInput(input)
+	if err != nil {
+		t.Fatalf("Failed to read JSON input: %v", err)
+	}
+
+	expectedSchema := bigquery.Schema{
+		{Name: "address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+			{Name: "city", Type: bigquery.StringFieldType},
+			{Name: "since", Type: bigquery.DateFieldType},
+		}},
+		{Name: "events", Type: bigquery.RecordFieldType, Repeated: true, Schema: bigquery.Schema{
+			{Name: "count", Type: bigquery.FloatFieldType},
+			{Name: "kind", Type: bigquery.StringFieldType},
+		}},
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "name", Type: bigquery.StringFieldType},
+		{Name: "tags", Type: bigquery.StringFieldType, Repeated: true},
+		{Name: "score", Type: bigquery.FloatFieldType},
+	}
+	if !reflect.DeepEqual(schema, expectedSchema) {
+		t.Errorf("Unexpected schema:\n got %v\nwant %v", schema, expectedSchema)
+	}
+
+	expectedRow := []bigquery.Value{
+		[]bigquery.Value{"Paris", civil.Date{Year: 2020, Month: 1, Day: 1}},
+		[]bigquery.Value{[]bigquery.Value{2.0, "click"}},
+		int64(1),
+		"foo",
+		[]bigquery.Value{"a", "b"},
+		nil,
+	}
+	if !reflect.DeepEqual(rows[0], expectedRow) {
+		t.Errorf("Unexpected row:\n got %v\nwant %v", rows[0], expectedRow)
+	}
+}
+
+func TestReadJSONInputObjectOverrides(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "jsoninput")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	content := `{"id": 1, "payload": {"b": [1, 2], "a": true}, "meta": {"source": "web"}}
+{"id": 2, "payload": "[1]", "meta": null}
+`
+	path := filepath.Join(tmpDir, "events.ndjson")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	input := &models.Input{
+		TableName:       "events",
+		File:            path,
+		SchemaOverrides: map[string]string{"payload": "JSON", "meta": "STRING"},
+	}
+
+	schema, rows, err := readJSONInput(input)
+	if err != nil {
+		t.Fatalf("Failed to read JSON input: %v", err)
+	}
+
+	expectedSchema := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "meta", Type: bigquery.StringFieldType},
+		{Name: "payload", Type: bigquery.JSONFieldType},
+	}
+	if !reflect.DeepEqual(schema, expectedSchema) {
+		t.Errorf("Unexpected schema:\n got %v\nwant %v", schema, expectedSchema)
+	}
+
+	expectedRows := [][]bigquery.Value{
+		{int64(1), `{"source":"web"}`, `{"a":true,"b":[1,2]}`},
+		{int64(2), nil, `"[1]"`},
+	}
+	if !reflect.DeepEqual(rows, expectedRows) {
+		t.Errorf("Unexpected rows:\n got %v\nwant %v", rows, expectedRows)
+	}
+
+	input.SchemaOverrides = map[string]string{"meta": "INTEGER"}
+	if _, _, err := readJSONInput(input); err == nil {
+		t.Error("Expected an error due to an object overridden as INTEGER, got none")
+	}
+}
+
+func TestConvertJSONRecordNullElement(t *testing.T) {
+	schema := bigquery.Schema{{Name: "tags", Type: bigquery.StringFieldType, Repeated: true}}
+	record := map[string]interface{}{"tags": []interface{}{"a", nil}}
+
+	if _, err := convertJSONRecord(record, schema); err == nil {
+		t.Error("Expected an error due to a null array element, got none")
+	}
+}
+
+func TestInferJSONSchemaErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []map[string]interface{}
+	}{
+		{"Mixed types", []map[string]interface{}{{"a": "x"}, {"a": true}}},
+		{"Mixed arrays and values", []map[string]interface{}{{"a": []interface{}{"x"}}, {"a": "y"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := inferJSONSchema(tt.records, "", nil); err == nil {
+				t.Error("Expected an error, got none")
+			}
+		})
+	}
+}
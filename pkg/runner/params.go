@@ -0,0 +1,119 @@
+package runner
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/JoseTorrado/bqtest/pkg/models"
+)
+
+// queryParameters converts the test's parameters into BigQuery query parameters
+func queryParameters(params []models.Param) ([]bigquery.QueryParameter, error) {
+	var parameters []bigquery.QueryParameter
+	for i, param := range params {
+		// Positional parameters are identified by their position
+		label := fmt.Sprintf("'%s'", param.Name)
+		if param.Name == "" {
+			label = fmt.Sprintf("%d", i+1)
+		}
+
+		dataType, err := models.ParseParamType(param.Type)
+		if err != nil {
+			return nil, fmt.Errorf("query parameter %s: %v", label, err)
+		}
+		value, err := paramValue(dataType, param.Value)
+		if err != nil {
+			return nil, fmt.Errorf("query parameter %s: %v", label, err)
+		}
+		parameters = append(parameters, bigquery.QueryParameter{Name: param.Name, Value: value})
+	}
+	return parameters, nil
+}
+
+// paramValue checks that value matches dataType and converts it into a typed
+// query parameter value. Scalars are sent in their text form.
+func paramValue(dataType *bigquery.StandardSQLDataType, value models.ParamValue) (*bigquery.QueryParameterValue, error) {
+	pv := &bigquery.QueryParameterValue{Type: *dataType}
+	switch {
+	case value.IsNull():
+		pv.Value = nullParamValue(dataType)
+
+	case dataType.ArrayElementType != nil:
+		if value.Array == nil {
+			return nil, fmt.Errorf("expected a list of values, got '%s'", value)
+		}
+		// The client only sends an empty array when it has a value
+		pv.Value = []string{}
+		for i, element := range value.Array {
+			v, err := paramValue(dataType.ArrayElementType, element)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %v", i, err)
+			}
+			pv.ArrayValue = append(pv.ArrayValue, *v)
+		}
+
+	case dataType.StructType != nil:
+		if value.Struct == nil {
+			return nil, fmt.Errorf("expected a map of field values, got '%s'", value)
+		}
+		fields := make(map[string]bool)
+		pv.StructValue = make(map[string]bigquery.QueryParameterValue)
+		for _, field := range dataType.StructType.Fields {
+			// Fields without a value are NULL
+			v, err := paramValue(field.Type, value.Struct[field.Name])
+			if err != nil {
+				return nil, fmt.Errorf("field '%s': %v", field.Name, err)
+			}
+			pv.StructValue[field.Name] = *v
+			fields[field.Name] = true
+		}
+		for name := range value.Struct {
+			if !fields[name] {
+				return nil, fmt.Errorf("unknown field '%s'", name)
+			}
+		}
+
+	default:
+		if value.Scalar == nil {
+			return nil, fmt.Errorf("expected a single %s value, got '%s'", dataType.TypeKind, value)
+		}
+		fieldType, err := models.ParseFieldType(dataType.TypeKind)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := convertValue(*value.Scalar, fieldType); err != nil {
+			return nil, fmt.Errorf("failed to convert value: %v", err)
+		}
+		pv.Value = *value.Scalar
+	}
+	return pv, nil
+}
+
+// nullParamValue returns the NULL value of a parameter of the given type. The
+// client rejects nil values, so NULL is sent as the client's null type matching
+// the declared type, or as a NULL STRING value for types without one, which
+// the declared parameter type still applies to.
+func nullParamValue(dataType *bigquery.StandardSQLDataType) interface{} {
+	switch dataType.TypeKind {
+	case "INT64":
+		return bigquery.NullInt64{}
+	case "FLOAT64":
+		return bigquery.NullFloat64{}
+	case "BOOL":
+		return bigquery.NullBool{}
+	case "TIMESTAMP":
+		return bigquery.NullTimestamp{}
+	case "DATE":
+		return bigquery.NullDate{}
+	case "TIME":
+		return bigquery.NullTime{}
+	case "DATETIME":
+		return bigquery.NullDateTime{}
+	case "GEOGRAPHY":
+		return bigquery.NullGeography{}
+	case "JSON":
+		return bigquery.NullJSON{}
+	default:
+		return bigquery.NullString{}
+	}
+}
@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/JoseTorrado/bqtest/pkg/models"
+)
+
+func scalar(s string) models.ParamValue {
+	return models.ParamValue{Scalar: &s}
+}
+
+func TestQueryParameters(t *testing.T) {
+	params := []models.Param{
+		{Name: "start_date", Type: "DATE", Value: scalar("2024-01-01")},
+		{Name: "ids", Type: "ARRAY<INT64>", Value: models.ParamValue{Array: []models.ParamValue{scalar("1"), scalar("2")}}},
+		{Name: "filter", Type: "STRUCT<min INT64, label STRING>", Value: models.ParamValue{Struct: map[string]models.ParamValue{"min": scalar("1")}}},
+		{Name: "end_date", Type: "DATE"},
+		{Name: "limit", Type: "INT64"},
+	}
+
+	parameters, err := queryParameters(params)
+	if err != nil {
+		t.Fatalf("Failed to convert params: %v", err)
+	}
+	if len(parameters) != len(params) {
+		t.Fatalf("Expected %d parameters, got %d", len(params), len(parameters))
+	}
+
+	date := parameters[0].Value.(*bigquery.QueryParameterValue)
+	if parameters[0].Name != "start_date" || date.Type.TypeKind != "DATE" || date.Value != "2024-01-01" {
+		t.Errorf("Unexpected DATE parameter %+v", date)
+	}
+	ids := parameters[1].Value.(*bigquery.QueryParameterValue)
+	if len(ids.ArrayValue) != 2 || ids.ArrayValue[1].Value != "2" {
+		t.Errorf("Unexpected ARRAY parameter %+v", ids)
+	}
+	filter := parameters[2].Value.(*bigquery.QueryParameterValue)
+	if filter.StructValue["min"].Value != "1" || filter.StructValue["label"].Value != (bigquery.NullString{}) {
+		t.Errorf("Unexpected STRUCT parameter %+v", filter)
+	}
+	// NULL parameters keep their declared type
+	endDate := parameters[3].Value.(*bigquery.QueryParameterValue)
+	if endDate.Type.TypeKind != "DATE" || endDate.Value != (bigquery.NullDate{}) {
+		t.Errorf("Expected a NULL DATE parameter, got %+v", endDate)
+	}
+	limit := parameters[4].Value.(*bigquery.QueryParameterValue)
+	if limit.Type.TypeKind != "INT64" || limit.Value != (bigquery.NullInt64{}) {
+		t.Errorf("Expected a NULL INT64 parameter, got %+v", limit)
+	}
+
+	invalid := [][]models.Param{
+		{{Name: "n", Type: "INT64", Value: scalar("one")}},
+		{{Name: "ids", Type: "ARRAY<INT64>", Value: scalar("1")}},
+		{{Name: "n", Type: "INT64", Value: models.ParamValue{Array: []models.ParamValue{}}}},
+		{{Name: "s", Type: "STRUCT<a INT64>", Value: models.ParamValue{Struct: map[string]models.ParamValue{"b": scalar("1")}}}},
+	}
+	for _, params := range invalid {
+		if _, err := queryParameters(params); err == nil {
+			t.Errorf("Expected an error for %+v, got none", params[0])
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package runner
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// Results holds the rows returned by a test query along with their schema
+type Results struct {
+	Schema     bigquery.Schema
+	Rows       [][]bigquery.Value
+	NullMarker string // text rendered for NULL values
+}
+
+// Header returns the column names of the results
+func (res *Results) Header() []string {
+	header := make([]string, len(res.Schema))
+	for i, field := range res.Schema {
+		header[i] = field.Name
+	}
+	return header
+}
+
+// Table renders the results as strings, with the header as the first row,
+// matching the layout of the expected CSV files.
+func (res *Results) Table() [][]string {
+	table := [][]string{res.Header()}
+	for _, row := range res.Rows {
+		record := make([]string, len(row))
+		for i, v := range row {
+			record[i] = formatCell(v, res.NullMarker)
+		}
+		table = append(table, record)
+	}
+	return table
+}
+
+// Values renders the data rows of the results like Table, with nil for NULL
+func (res *Results) Values() [][]*string {
+	values := make([][]*string, len(res.Rows))
+	for i, row := range res.Rows {
+		values[i] = make([]*string, len(row))
+		for j, v := range row {
+			if v != nil {
+				values[i][j] = stringPtr(formatValue(v))
+			}
+		}
+	}
+	return values
+}
+
+// formatCell renders a top-level value, using nullMarker for NULL
+func formatCell(v bigquery.Value, nullMarker string) string {
+	if v == nil {
+		return nullMarker
+	}
+	return formatValue(v)
+}
+
+// formatValue renders a single BigQuery value in its canonical text form.
+// Timestamps are normalized to UTC.
+func formatValue(v bigquery.Value) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case *big.Rat:
+		return v.FloatString(decimalScale(v))
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// decimalScale returns the number of fractional digits needed to print r exactly
+func decimalScale(r *big.Rat) int {
+	if n, exact := r.FloatPrec(); exact {
+		return n
+	}
+	return bigquery.BigNumericScaleDigits
+}
@@ -0,0 +1,46 @@
+package runner
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+)
+
+func TestResultsTable(t *testing.T) {
+	results := &Results{
+		Schema: bigquery.Schema{
+			{Name: "id", Type: bigquery.IntegerFieldType},
+			{Name: "score", Type: bigquery.FloatFieldType},
+			{Name: "amount", Type: bigquery.NumericFieldType},
+			{Name: "created_at", Type: bigquery.TimestampFieldType},
+		},
+		Rows: [][]bigquery.Value{
+			{int64(1), 2.0, big.NewRat(5, 2), time.Date(2024, 1, 1, 13, 0, 0, 0, time.FixedZone("CET", 3600))},
+		},
+	}
+
+	expected := [][]string{
+		{"id", "score", "amount", "created_at"},
+		{"1", "2", "2.5", "2024-01-01T12:00:00Z"},
+	}
+
+	if table := results.Table(); !reflect.DeepEqual(table, expected) {
+		t.Errorf("Expected table %v, got %v", expected, table)
+	}
+}
+
+func TestResultsValues(t *testing.T) {
+	results := &Results{
+		Schema:     bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}, {Name: "name", Type: bigquery.StringFieldType}},
+		Rows:       [][]bigquery.Value{{int64(1), nil}, {nil, ""}},
+		NullMarker: "NULL",
+	}
+
+	expected := [][]*string{{stringPtr("1"), nil}, {nil, stringPtr("")}}
+	if values := results.Values(); !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected values %v, got %v", expected, values)
+	}
+}
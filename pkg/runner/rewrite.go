@@ -0,0 +1,151 @@
+package runner
+
+import "strings"
+
+// rewriteTables replaces every table reference that matches a key of
+// replacements, such as `prod-project.analytics.users` or analytics.users, with
+// its replacement. References must match a key exactly, whether they are quoted
+// as a whole, quoted per part or not quoted at all. String literals, comments
+// and query parameters are never rewritten.
+func rewriteTables(query string, replacements map[string]string) string {
+	if len(replacements) == 0 {
+		return query
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(query); {
+		start := i
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			i = skipString(query, i, false)
+		case stringPrefixLen(query, i) > 0:
+			n := stringPrefixLen(query, i)
+			raw := strings.ContainsAny(query[i:i+n], "rR")
+			i = skipString(query, i+n, raw)
+		case c == '#' || strings.HasPrefix(query[i:], "--"):
+			i = skipUntil(query, i, "\n")
+		case strings.HasPrefix(query[i:], "/*"):
+			i = skipUntil(query, i+2, "*/")
+		case c == '@':
+			// Named parameters and system variables aren't table references
+			i++
+			for i < len(query) && (query[i] == '@' || isIdentChar(query[i])) {
+				i++
+			}
+		case c == '`' || isIdentStart(c):
+			var parts []string
+			i, parts = scanPath(query, i)
+			if replacement, ok := replacements[strings.Join(parts, ".")]; ok {
+				out.WriteString(replacement)
+				continue
+			}
+		case isIdentChar(c):
+			// Numbers, so 1.5 isn't mistaken for a path
+			for i < len(query) && (isIdentChar(query[i]) || query[i] == '.') {
+				i++
+			}
+		default:
+			i++
+		}
+		out.WriteString(query[start:i])
+	}
+	return out.String()
+}
+
+// scanPath reads a dotted path of quoted and unquoted identifiers starting at i
+// and returns the position after it along with its parts
+func scanPath(query string, i int) (int, []string) {
+	var parts []string
+	for {
+		if query[i] == '`' {
+			end := skipString(query, i, false)
+			quoted := strings.TrimSuffix(query[i+1:end], "`")
+			parts = append(parts, strings.Split(quoted, ".")...)
+			i = end
+		} else {
+			start := i
+			i = scanIdent(query, i, false)
+			// Only a project name, the first part of a longer path, has hyphens
+			if end := scanIdent(query, start, true); len(parts) == 0 && end > i && continuesPath(query, end) {
+				i = end
+			}
+			parts = append(parts, query[start:i])
+		}
+
+		// Continue with the next part of the path
+		if continuesPath(query, i) {
+			i++
+			continue
+		}
+		return i, parts
+	}
+}
+
+// scanIdent returns the position after the unquoted identifier starting at i,
+// with hyphens joining its characters when hyphens is set
+func scanIdent(query string, i int, hyphens bool) int {
+	for i < len(query) && (isIdentChar(query[i]) || hyphens && isIdentHyphen(query, i)) {
+		i++
+	}
+	return i
+}
+
+// continuesPath reports whether a dot at i is followed by another part of a path
+func continuesPath(query string, i int) bool {
+	return i+1 < len(query) && query[i] == '.' && (query[i+1] == '`' || isIdentStart(query[i+1]))
+}
+
+// skipString returns the position after the quoted string or identifier
+// starting at i, including triple-quoted strings and backslash escapes unless
+// the string is raw
+func skipString(query string, i int, raw bool) int {
+	quote := query[i : i+1]
+	if strings.HasPrefix(query[i:], strings.Repeat(quote, 3)) && quote != "`" {
+		quote = strings.Repeat(quote, 3)
+	}
+	for i += len(quote); i < len(query); i++ {
+		if query[i] == '\\' && !raw {
+			i++
+			continue
+		}
+		if strings.HasPrefix(query[i:], quote) {
+			return i + len(quote)
+		}
+	}
+	return len(query)
+}
+
+// stringPrefixLen returns the length of the r, b, rb or br prefix of a string
+// literal starting at i, in any case, or 0 if there is none
+func stringPrefixLen(query string, i int) int {
+	for _, prefix := range []string{"rb", "br", "r", "b"} {
+		n := len(prefix)
+		if len(query) > i+n && strings.EqualFold(query[i:i+n], prefix) && (query[i+n] == '\'' || query[i+n] == '"') {
+			return n
+		}
+	}
+	return 0
+}
+
+// skipUntil returns the position after the first end at or after i, or the end of the query
+func skipUntil(query string, i int, end string) int {
+	if j := strings.Index(query[i:], end); j >= 0 {
+		return i + j + len(end)
+	}
+	return len(query)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// isIdentHyphen reports whether the hyphen at i joins two parts of an unquoted
+// project name such as prod-project, rather than starting a comment
+func isIdentHyphen(query string, i int) bool {
+	return query[i] == '-' && i > 0 && isIdentChar(query[i-1]) && i+1 < len(query) && isIdentChar(query[i+1])
+}
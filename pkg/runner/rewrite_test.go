@@ -0,0 +1,70 @@
+package runner
+
+import "testing"
+
+func TestRewriteTables(t *testing.T) {
+	replacements := map[string]string{
+		"prod-project.analytics.users": "`ds.users`",
+		"analytics.orders":             "`ds.orders`",
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{
+			name:     "Quoted fully-qualified reference",
+			query:    "SELECT * FROM `prod-project.analytics.users` u",
+			expected: "SELECT * FROM `ds.users` u",
+		},
+		{
+			name:     "Quoted parts",
+			query:    "SELECT * FROM `prod-project`.analytics.`users`",
+			expected: "SELECT * FROM `ds.users`",
+		},
+		{
+			name:     "Unquoted references",
+			query:    "SELECT * FROM prod-project.analytics.users JOIN analytics.orders o ON o.user_id = users.id",
+			expected: "SELECT * FROM `ds.users` JOIN `ds.orders` o ON o.user_id = users.id",
+		},
+		{
+			name:     "String literals and comments",
+			query:    "SELECT 'analytics.orders', \"\"\"`analytics.orders`\"\"\" -- analytics.orders\n/* analytics.orders */ # analytics.orders\nFROM analytics.orders",
+			expected: "SELECT 'analytics.orders', \"\"\"`analytics.orders`\"\"\" -- analytics.orders\n/* analytics.orders */ # analytics.orders\nFROM `ds.orders`",
+		},
+		{
+			name:     "Escaped quote",
+			query:    `SELECT 'it\'s analytics.orders' FROM analytics.orders`,
+			expected: "SELECT 'it\\'s analytics.orders' FROM `ds.orders`",
+		},
+		{
+			name:     "Raw strings",
+			query:    `SELECT r'\', R"analytics.orders\", rb'\' FROM analytics.orders`,
+			expected: "SELECT r'\\', R\"analytics.orders\\\", rb'\\' FROM `ds.orders`",
+		},
+		{
+			name:     "Hyphens outside a project name",
+			query:    "SELECT prod-project, analytics.orders-orders.id FROM analytics.orders",
+			expected: "SELECT prod-project, `ds.orders`-orders.id FROM `ds.orders`",
+		},
+		{
+			name:     "Longer and partial paths",
+			query:    "SELECT other.analytics.orders, analytics.orders_v2, orders FROM x",
+			expected: "SELECT other.analytics.orders, analytics.orders_v2, orders FROM x",
+		},
+		{
+			name:     "Parameters and numbers",
+			query:    "SELECT 1.5, @analytics FROM analytics.orders WHERE n > 2-1",
+			expected: "SELECT 1.5, @analytics FROM `ds.orders` WHERE n > 2-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rewriteTables(tt.query, replacements); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
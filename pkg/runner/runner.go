@@ -3,13 +3,11 @@ package runner
 import (
 	"context"
 	"fmt"
-	"strconv"
-	"strings"
-	"time"
-	"unicode"
+	"path/filepath"
+	"slices"
+	"sync"
 
 	"cloud.google.com/go/bigquery"
-	"cloud.google.com/go/civil"
 	"github.com/JoseTorrado/bqtest/pkg/fileutil"
 	"github.com/JoseTorrado/bqtest/pkg/models"
 	"github.com/goccy/bigquery-emulator/server"
@@ -21,20 +19,33 @@ import (
 type TestRunner struct {
 	Client *bigquery.Client
 	server *server.Server
+
+	mu       sync.Mutex
+	datasets map[*models.Test]string     // isolated dataset of every test
+	created  int                         // number of isolated datasets handed out
+	clients  map[string]*bigquery.Client // clients of projects other than the default
+	locks    map[string]*sync.Mutex      // locks of the project datasets inputs are declared in
 }
 
 const (
+	testProjectID = "test-project"
 	testDatasetID = "test_dataset"
 )
 
+// Project is an emulator project along with the datasets created in it up front
+type Project struct {
+	ID       string
+	Datasets []string
+}
+
 // ValueSaverRow implements the ValueSaver interface
 type ValueSaverRow struct {
 	Row []bigquery.Value
 }
 
-func NewTestRunner() (*TestRunner, error) {
-	ctx := context.Background()
-
+// NewTestRunner starts the emulator with the default test project and the given
+// projects, which tests can declare their inputs in. See ProjectsFor.
+func NewTestRunner(projects ...Project) (*TestRunner, error) {
 	// Start the bigquery emulator
 	srv, err := server.New(server.TempStorage)
 	if err != nil {
@@ -43,31 +54,189 @@ func NewTestRunner() (*TestRunner, error) {
 
 	srv.SetLogLevel("error")
 
-	// Create a test project
-	if err := srv.Load(server.StructSource(types.NewProject("test-project"))); err != nil {
-		return nil, fmt.Errorf("Failed to create test project: %v", err)
+	// Create the test project and every declared project
+	emulated := []*types.Project{types.NewProject(testProjectID)}
+	for _, project := range projects {
+		var datasets []*types.Dataset
+		for _, id := range project.Datasets {
+			datasets = append(datasets, types.NewDataset(id))
+		}
+		if project.ID == testProjectID {
+			emulated[0] = types.NewProject(testProjectID, datasets...)
+			continue
+		}
+		emulated = append(emulated, types.NewProject(project.ID, datasets...))
+	}
+	if err := srv.Load(server.StructSource(emulated...)); err != nil {
+		return nil, fmt.Errorf("Failed to create test projects: %v", err)
 	}
 
+	r := &TestRunner{server: srv}
+
 	// Create a BigQuery client that connects to the emulator
+	r.Client, err = r.newClient(testProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// ProjectsFor returns the projects and datasets the tests declare their inputs
+// in, along with the projects their queries run in
+func ProjectsFor(tests []models.Test) []Project {
+	var projects []Project
+	index := make(map[string]int)
+	add := func(projectID, datasetID string) {
+		i, ok := index[projectID]
+		if !ok {
+			i = len(projects)
+			index[projectID] = i
+			projects = append(projects, Project{ID: projectID})
+		}
+		if datasetID != "" && !slices.Contains(projects[i].Datasets, datasetID) {
+			projects[i].Datasets = append(projects[i].Datasets, datasetID)
+		}
+	}
+
+	for i := range tests {
+		test := &tests[i]
+		add(queryProject(test), "")
+		for _, input := range test.GetInputs() {
+			if input.Dataset != "" {
+				add(inputProject(test, input), input.Dataset)
+			}
+		}
+	}
+	return projects
+}
+
+// queryProject returns the default project of the test's query
+func queryProject(test *models.Test) string {
+	if test.Project != "" {
+		return test.Project
+	}
+	return testProjectID
+}
+
+// inputProject returns the project an input with a declared dataset belongs to
+func inputProject(test *models.Test, input models.Input) string {
+	if input.Project != "" {
+		return input.Project
+	}
+	return queryProject(test)
+}
+
+func (r *TestRunner) newClient(projectID string) (*bigquery.Client, error) {
 	client, err := bigquery.NewClient(
-		ctx,
-		"test-project",
-		option.WithEndpoint(srv.TestServer().URL),
+		context.Background(),
+		projectID,
+		option.WithEndpoint(r.server.TestServer().URL),
 		option.WithoutAuthentication(),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to create BigQuery CLient: %v", err)
 	}
+	return client, nil
+}
+
+// clientFor returns a client whose queries run in projectID
+func (r *TestRunner) clientFor(projectID string) (*bigquery.Client, error) {
+	if projectID == testProjectID {
+		return r.Client, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	return &TestRunner{
-		Client: client,
-		server: srv,
-	}, nil
+	if client, ok := r.clients[projectID]; ok {
+		return client, nil
+	}
+	client, err := r.newClient(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if r.clients == nil {
+		r.clients = make(map[string]*bigquery.Client)
+	}
+	r.clients[projectID] = client
+	return client, nil
 }
 
-// Add this new method to TestRunner
-func (r *TestRunner) ensureDatasetExists(ctx context.Context) error {
-	dataset := r.Client.Dataset(testDatasetID)
+// lockDatasets locks the project datasets the test declares inputs in, so tests
+// sharing a dataset don't see each other's tables, and returns the unlock function
+func (r *TestRunner) lockDatasets(test *models.Test) func() {
+	var names []string
+	for _, input := range test.GetInputs() {
+		if input.Dataset != "" {
+			names = append(names, inputProject(test, input)+"."+input.Dataset)
+		}
+	}
+	// A fixed locking order prevents deadlocks between tests
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	r.mu.Lock()
+	if r.locks == nil {
+		r.locks = make(map[string]*sync.Mutex)
+	}
+	locks := make([]*sync.Mutex, len(names))
+	for i, name := range names {
+		if r.locks[name] == nil {
+			r.locks[name] = &sync.Mutex{}
+		}
+		locks[i] = r.locks[name]
+	}
+	r.mu.Unlock()
+
+	for _, lock := range locks {
+		lock.Lock()
+	}
+	return func() {
+		for _, lock := range locks {
+			lock.Unlock()
+		}
+	}
+}
+
+// datasetFor returns the dataset the test's tables live in. Every test gets its
+// own dataset so tests using the same table names can run concurrently.
+func (r *TestRunner) datasetFor(test *models.Test) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.datasets == nil {
+		r.datasets = make(map[*models.Test]string)
+	}
+	if id, ok := r.datasets[test]; ok {
+		return id
+	}
+	id := r.nextDatasetID()
+	r.datasets[test] = id
+	return id
+}
+
+// releaseDataset forgets the dataset handed out to the test, once RunTest is done with it
+func (r *TestRunner) releaseDataset(test *models.Test) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.datasets, test)
+}
+
+// newDatasetID returns the ID of a new isolated dataset, such as a fixture's
+func (r *TestRunner) newDatasetID() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.nextDatasetID()
+}
+
+// nextDatasetID returns an isolated dataset ID not handed out before. r.mu must be held.
+func (r *TestRunner) nextDatasetID() string {
+	r.created++
+	return fmt.Sprintf("%s_%d", testDatasetID, r.created)
+}
+
+func (r *TestRunner) ensureDatasetExists(ctx context.Context, dataset *bigquery.Dataset) error {
 	meta, err := dataset.Metadata(ctx)
 	if err != nil {
 		// If the dataset doesn't exist, create it
@@ -81,127 +250,329 @@ func (r *TestRunner) ensureDatasetExists(ctx context.Context) error {
 	return nil
 }
 
+// dropDataset deletes a test's dataset along with its tables
+func (r *TestRunner) dropDataset(ctx context.Context, datasetID string) error {
+	if err := r.Client.Dataset(datasetID).DeleteWithContents(ctx); err != nil {
+		return fmt.Errorf("failed to delete dataset: %v", err)
+	}
+	return nil
+}
+
+// LoadTestData creates and populates every input table of the test, in its
+// isolated dataset unless the input declares its own dataset
 func (r *TestRunner) LoadTestData(test *models.Test) error {
-	ctx := context.Background()
+	_, err := r.loadTestData(context.Background(), test)
+	return err
+}
 
-	// Ensure the dataset exists
-	if err := r.ensureDatasetExists(ctx); err != nil {
-		return err
-	}
+// loadTestData loads the test's inputs like LoadTestData and reports whether
+// the test's isolated dataset was created, which it may be even on error
+func (r *TestRunner) loadTestData(ctx context.Context, test *models.Test) (bool, error) {
+	isolated := false
+	for _, input := range test.GetInputs() {
+		if test.InferSchema {
+			input.InferSchema = true
+		}
+		dataset := r.inputDataset(test, input)
 
-	// Read the CSV file
-	records, err := fileutil.ReadCSVFile(test.InputFile)
-	if err != nil {
-		return fmt.Errorf("failed to read input CSV: %v", err)
+		// Ensure the dataset exists
+		if err := r.ensureDatasetExists(ctx, dataset); err != nil {
+			return isolated, err
+		}
+		if input.Dataset == "" {
+			isolated = true
+		}
+		if err := r.loadInput(ctx, dataset, &input, test.GetNullMarker()); err != nil {
+			return isolated, fmt.Errorf("table '%s': %v", input.TableName, err)
+		}
 	}
 
-	if len(records) < 2 {
-		return fmt.Errorf("CSV file must contain at least a header row and one data row")
-	}
+	return isolated, nil
+}
 
-	headers := records[0]
+// inputDataset returns the dataset an input table is loaded into
+func (r *TestRunner) inputDataset(test *models.Test, input models.Input) *bigquery.Dataset {
+	if input.Dataset != "" {
+		return r.Client.DatasetInProject(inputProject(test, input), input.Dataset)
+	}
+	return r.Client.Dataset(r.datasetFor(test))
+}
 
-	// Create schema based on the CSV headers and overrides
-	schema := bigquery.Schema{}
-	for _, header := range headers {
-		fieldType := bigquery.StringFieldType // Default to string
-		if override, ok := test.SchemaOverrides[header]; ok {
-			fieldType = getBigQueryFieldType(override)
+// dropTables deletes the input tables a test loaded into declared datasets,
+// which outlive the test
+func (r *TestRunner) dropTables(ctx context.Context, test *models.Test) {
+	for _, input := range test.GetInputs() {
+		if input.Dataset != "" {
+			r.inputDataset(test, input).Table(input.TableName).Delete(ctx)
 		}
-		schema = append(schema, &bigquery.FieldSchema{
-			Name: formatFieldName(header),
-			Type: fieldType,
-		})
+	}
+}
+
+func (r *TestRunner) loadInput(ctx context.Context, dataset *bigquery.Dataset, input *models.Input, nullMarker string) error {
+	var schema bigquery.Schema
+	var rows [][]bigquery.Value
+	var err error
+	switch {
+	case input.Rows != nil:
+		schema, rows, err = readCSVInput(input, nullMarker)
+	case filepath.Ext(input.File) == ".json" || filepath.Ext(input.File) == ".ndjson":
+		schema, rows, err = readJSONInput(input)
+	default:
+		schema, rows, err = readCSVInput(input, nullMarker)
+	}
+	if err != nil {
+		return err
 	}
 
+	return r.createTable(ctx, dataset, input.TableName, schema, rows)
+}
+
+// createTable creates a table with the given schema and inserts rows into it
+func (r *TestRunner) createTable(ctx context.Context, dataset *bigquery.Dataset, tableName string, schema bigquery.Schema, rows [][]bigquery.Value) error {
 	// Create the table
-	tableRef := r.Client.Dataset(testDatasetID).Table(test.TableName)
+	tableRef := dataset.Table(tableName)
 	if err := tableRef.Create(ctx, &bigquery.TableMetadata{Schema: schema}); err != nil {
 		return fmt.Errorf("failed to create table: %v", err)
 	}
 
 	// Prepare the data for insertion
-	var rows []map[string]bigquery.Value
-	for _, record := range records[1:] { // Skip the header row
-		row := make(map[string]bigquery.Value)
+	savers := make([]*bigquery.ValuesSaver, len(rows))
+	for i, row := range rows {
+		savers[i] = &bigquery.ValuesSaver{Schema: schema, Row: row}
+	}
+
+	// Insert the data
+	inserter := tableRef.Inserter()
+	if err := inserter.Put(ctx, savers); err != nil {
+		return fmt.Errorf("failed to insert data: %v", err)
+	}
+
+	return nil
+}
+
+// readCSVInput reads a CSV input file, or the input's inline rows, into a flat
+// schema and typed rows. Cells equal to nullMarker, empty cells of non-STRING
+// columns and null inline cells are loaded as NULL, which REQUIRED fields reject.
+func readCSVInput(input *models.Input, nullMarker string) (bigquery.Schema, [][]bigquery.Value, error) {
+	records, err := inputRecords(input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(records) < 2 {
+		return nil, nil, fmt.Errorf("CSV file must contain at least a header row and one data row")
+	}
+
+	headers := records[0]
+	if err := checkCSVHeaders(headers); err != nil {
+		return nil, nil, err
+	}
+
+	schema, positions, err := csvSchema(input, records, nullMarker)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows [][]bigquery.Value
+	for r, record := range records[1:] { // Skip the header row
+		row := make([]bigquery.Value, len(schema))
 		for i, value := range record {
 			if i < len(headers) {
-				convertedValue, err := convertValue(value, schema[i].Type)
+				field := schema[positions[i]]
+				if isNull(value, field.Type, nullMarker) || (input.Rows != nil && input.Rows.IsNull(r, i)) {
+					if field.Required {
+						return nil, nil, fmt.Errorf("row %d: missing value for REQUIRED field '%s'", r+1, field.Name)
+					}
+					continue
+				}
+				convertedValue, err := convertValue(value, field.Type)
 				if err != nil {
-					return fmt.Errorf("failed to convert value: %v", err)
+					return nil, nil, fmt.Errorf("failed to convert value: %v", err)
 				}
-				row[formatFieldName(headers[i])] = convertedValue
+				row[positions[i]] = convertedValue
 			}
 		}
 		rows = append(rows, row)
 	}
 
-	// Insert the data
-	inserter := tableRef.Inserter()
-	if err := inserter.Put(ctx, rows); err != nil {
-		return fmt.Errorf("failed to insert data: %v", err)
+	return schema, rows, nil
+}
+
+// inputRecords returns the input's rows as text, header first
+func inputRecords(input *models.Input) ([][]string, error) {
+	if input.Rows != nil {
+		return input.Rows.Records(), nil
 	}
 
-	return nil
+	// Read the CSV file
+	records, err := fileutil.ReadCSVFile(input.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input CSV: %v", err)
+	}
+	return records, nil
 }
 
-func formatFieldName(s string) string {
-	// Capitalize the first letter and remove any non-alphanumeric characters
-	r := []rune(s)
-	return string(append([]rune{unicode.ToUpper(r[0])}, r[1:]...))
-}
-
-func getBigQueryFieldType(typeString string) bigquery.FieldType {
-	switch strings.ToUpper(typeString) {
-	case "INTEGER":
-		return bigquery.IntegerFieldType
-	case "FLOAT":
-		return bigquery.FloatFieldType
-	case "BOOLEAN":
-		return bigquery.BooleanFieldType
-	case "TIMESTAMP":
-		return bigquery.TimestampFieldType
-	case "DATE":
-		return bigquery.DateFieldType
-	default:
-		return bigquery.StringFieldType
-	}
-}
-
-func convertValue(value string, fieldType bigquery.FieldType) (bigquery.Value, error) {
-	switch fieldType {
-	case bigquery.IntegerFieldType:
-		return strconv.ParseInt(value, 10, 64)
-	case bigquery.FloatFieldType:
-		return strconv.ParseFloat(value, 64)
-	case bigquery.BooleanFieldType:
-		return strconv.ParseBool(value)
-	case bigquery.TimestampFieldType:
-		return time.Parse(time.RFC3339, value)
-	case bigquery.DateFieldType:
-		return civil.ParseDate(value)
-	default:
-		return value, nil
+// isNull reports whether a CSV cell stands for NULL. With a null marker only
+// the marker does; without one, empty cells of non-STRING columns do.
+func isNull(value string, fieldType bigquery.FieldType, nullMarker string) bool {
+	if nullMarker != "" {
+		return value == nullMarker
 	}
+	return value == "" && fieldType != bigquery.StringFieldType
 }
 
-// I am still shaky on this function... Need to look over it
-func (r *TestRunner) RunTest(test *models.Test) ([][]string, error) {
+// csvSchema returns the schema of a CSV input along with the schema position of
+// every CSV column. The schema comes from the input's schema file if it has one,
+// otherwise from the headers and schema overrides, with the types of the other
+// columns inferred from the records when the input opts in.
+func csvSchema(input *models.Input, records [][]string, nullMarker string) (bigquery.Schema, []int, error) {
+	headers := records[0]
+	if input.Schema != "" {
+		schema, err := readSchemaFile(input.Schema)
+		if err != nil {
+			return nil, nil, err
+		}
+		positions, err := matchCSVHeaders(headers, schema)
+		if err != nil {
+			return nil, nil, err
+		}
+		return schema, positions, nil
+	}
+
+	var inferred bigquery.Schema
+	if input.InferSchema {
+		// YAML null cells are empty in the records, which only stands for NULL without a marker
+		inferRecords := records
+		if input.Rows != nil {
+			inferRecords = input.Rows.RecordsWithNulls(nullMarker)
+		}
+		var err error
+		inferred, err = InferCSVSchema(inferRecords, nullMarker)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Create schema based on the CSV headers and overrides
+	schema := bigquery.Schema{}
+	positions := make([]int, len(headers))
+	for i, header := range headers {
+		var err error
+		fieldType := bigquery.StringFieldType // Default to string
+		if inferred != nil {
+			fieldType = inferred[i].Type
+		}
+		if override, ok := input.SchemaOverrides[header]; ok {
+			fieldType, err = models.ParseFieldType(override)
+			if err != nil {
+				return nil, nil, fmt.Errorf("schema override for '%s': %v", header, err)
+			}
+			// Input.Validate rejects these too, but fixtures aren't validated
+			if fieldType == bigquery.RecordFieldType {
+				return nil, nil, fmt.Errorf("schema override for '%s': RECORD fields are not supported in CSV inputs", header)
+			}
+		}
+		schema = append(schema, &bigquery.FieldSchema{
+			Name: header,
+			Type: fieldType,
+		})
+		positions[i] = i
+	}
+	return schema, positions, nil
+}
+
+// tableRefs returns the quoted reference of every input table of the test.
+// Tables in the isolated dataset are qualified with the test project when the
+// query runs in another project.
+func tableRefs(test *models.Test, datasetID string) map[string]string {
+	refs := make(map[string]string)
+	for _, input := range test.GetInputs() {
+		switch {
+		case input.Dataset != "":
+			refs[input.TableName] = fmt.Sprintf("`%s.%s.%s`", inputProject(test, input), input.Dataset, input.TableName)
+		case queryProject(test) != testProjectID:
+			refs[input.TableName] = fmt.Sprintf("`%s.%s.%s`", testProjectID, datasetID, input.TableName)
+		default:
+			refs[input.TableName] = fmt.Sprintf("`%s.%s`", datasetID, input.TableName)
+		}
+	}
+	return refs
+}
+
+// RenderQuery returns the test query as it is submitted to the emulator, with
+// every table placeholder and variable substituted. Tables are placed in the
+// shared test dataset rather than the isolated one RunTest loads them into, so
+// rendering doesn't reserve a dataset.
+func (r *TestRunner) RenderQuery(test *models.Test) (string, error) {
+	return renderQuery(test, testDatasetID)
+}
+
+// renderQuery renders the test query with the test's tables in the given dataset
+func renderQuery(test *models.Test, datasetID string) (string, error) {
+	query, err := test.GetQuery()
+	if err != nil {
+		return "", fmt.Errorf("failed to get query: %v", err)
+	}
+
+	// Replace table placeholders and production table references
+	refs := tableRefs(test, datasetID)
+	replacements := make(map[string]string, len(test.TableMappings))
+	for ref, table := range test.TableMappings {
+		if _, ok := refs[table]; !ok {
+			return "", fmt.Errorf("table mapping '%s' points at unknown input table '%s'", ref, table)
+		}
+		replacements[ref] = refs[table]
+	}
+	// Variables are rendered before production references are rewritten, as
+	// they may be part of one
+	query, err = renderVars(query, refs, test.Vars)
+	if err != nil {
+		return "", err
+	}
+	return rewriteTables(query, replacements), nil
+}
+
+// RunTest loads the test data, runs the query and returns the typed results.
+// The test's dataset is dropped afterwards, and tests sharing a declared dataset
+// take turns, so RunTest is safe to call concurrently.
+func (r *TestRunner) RunTest(test *models.Test) (*Results, error) {
 	ctx := context.Background()
+	client, err := r.clientFor(queryProject(test))
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := r.lockDatasets(test)
+	defer unlock()
+
 	// Load the test data
-	if err := r.LoadTestData(test); err != nil {
+	datasetID := r.datasetFor(test)
+	defer r.releaseDataset(test)
+	defer r.dropTables(ctx, test)
+	isolated, err := r.loadTestData(ctx, test)
+	if isolated {
+		defer r.dropDataset(ctx, datasetID)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to load test data: %v", err)
 	}
 
-	query, err := test.GetQuery()
+	query, err := renderQuery(test, datasetID)
+	if err != nil {
+		return nil, err
+	}
+	params, err := queryParameters(test.Params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get query: %v", err)
+		return nil, err
 	}
 
-	// Replace table name in query if necessary
-	query = strings.ReplaceAll(query, "${TABLE}", fmt.Sprintf("`%s.%s`", testDatasetID, test.TableName))
+	return r.runQuery(ctx, client, query, params, test.GetNullMarker())
+}
 
-	q := r.Client.Query(query)
+// runQuery runs a query with the given parameters and reads all of its rows
+func (r *TestRunner) runQuery(ctx context.Context, client *bigquery.Client, query string, params []bigquery.QueryParameter, nullMarker string) (*Results, error) {
+	q := client.Query(query)
+	q.Parameters = params
 	job, err := q.Run(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("Failed tu run query: %v", err)
@@ -221,7 +592,7 @@ func (r *TestRunner) RunTest(test *models.Test) ([][]string, error) {
 		return nil, fmt.Errorf("falied to read job results: %v", err)
 	}
 
-	var results [][]string
+	var rows [][]bigquery.Value
 	for {
 		var row []bigquery.Value
 		err := it.Next(&row)
@@ -231,42 +602,20 @@ func (r *TestRunner) RunTest(test *models.Test) ([][]string, error) {
 		if err != nil {
 			return nil, fmt.Errorf("Failed to iterate over results: %v", err)
 		}
-
-		stringRow := make([]string, len(row))
-		for i, v := range row {
-			stringRow[i] = fmt.Sprintf("%v", v)
-		}
-		results = append(results, stringRow)
+		rows = append(rows, row)
 	}
 
-	return results, nil
+	// The schema is only populated once the iterator has fetched the first page
+	return &Results{Schema: it.Schema, Rows: rows, NullMarker: nullMarker}, nil
 }
 
-// also shaky on this one
-// CompareResults compares the actual results with the expected output
-func (r *TestRunner) CompareResults(actual, expected [][]string) (bool, []string) {
-	if len(actual) != len(expected) {
-		return false, []string{fmt.Sprintf("Row count mismatch: expected %d, got %d", len(expected), len(actual))}
-	}
-
-	var differences []string
-	for i := range actual {
-		if len(actual[i]) != len(expected[i]) {
-			differences = append(differences, fmt.Sprintf("Row %d: Column count mismatch: expected %d, got %d", i, len(expected[i]), len(actual[i])))
-			continue
-		}
-		for j := range actual[i] {
-			if actual[i][j] != expected[i][j] {
-				differences = append(differences, fmt.Sprintf("Row %d, Column %d: expected '%s', got '%s'", i, j, expected[i][j], actual[i][j]))
-			}
+// Close closes the BigQuery clients and stops the emulator
+func (r *TestRunner) Close() error {
+	for _, client := range r.clients {
+		if err := client.Close(); err != nil {
+			return err
 		}
 	}
-
-	return len(differences) == 0, differences
-}
-
-// Close closes the BigQuery client and stops the emulator
-func (r *TestRunner) Close() error {
 	if err := r.Client.Close(); err != nil {
 		return err
 	}
@@ -2,7 +2,11 @@ package runner
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
 	"testing"
 
 	"cloud.google.com/go/bigquery"
@@ -55,13 +59,15 @@ func TestRunTest(t *testing.T) {
 	}
 
 	// Run the test
-	results, err := runner.RunTest(test)
+	actual, err := runner.RunTest(test)
 	if err != nil {
 		t.Fatalf("RunTest failed: %v", err)
 	}
+	results := actual.Table()
 
 	// Check the results
 	expected := [][]string{
+		{"id", "name"},
 		{"1", "foo"},
 		{"2", "bar"},
 	}
@@ -82,55 +88,301 @@ func TestRunTest(t *testing.T) {
 	}
 }
 
-func TestCompareResults(t *testing.T) {
-	runner := &TestRunner{}
-
+func TestTableRefs(t *testing.T) {
 	tests := []struct {
-		name      string
-		actual    [][]string
-		expected  [][]string
-		match     bool
-		diffCount int
+		name     string
+		query    string
+		test     models.Test
+		expected string
 	}{
 		{
-			name:      "Exact match",
-			actual:    [][]string{{"1", "2"}, {"3", "4"}},
-			expected:  [][]string{{"1", "2"}, {"3", "4"}},
-			match:     true,
-			diffCount: 0,
+			name:     "Single input with TABLE placeholder",
+			query:    "SELECT * FROM ${TABLE}",
+			test:     models.Test{Inputs: []models.Input{{TableName: "users"}}},
+			expected: "SELECT * FROM `test_dataset_1.users`",
 		},
 		{
-			name:      "Different values",
-			actual:    [][]string{{"1", "2"}, {"3", "5"}},
-			expected:  [][]string{{"1", "2"}, {"3", "4"}},
-			match:     false,
-			diffCount: 1,
+			name:     "Multiple inputs",
+			query:    "SELECT * FROM ${users} u JOIN ${orders} o ON u.id = o.user_id",
+			test:     models.Test{Inputs: []models.Input{{TableName: "users"}, {TableName: "orders"}}},
+			expected: "SELECT * FROM `test_dataset_1.users` u JOIN `test_dataset_1.orders` o ON u.id = o.user_id",
 		},
 		{
-			name:      "Different row count",
-			actual:    [][]string{{"1", "2"}},
-			expected:  [][]string{{"1", "2"}, {"3", "4"}},
-			match:     false,
-			diffCount: 1,
+			name:  "Declared datasets",
+			query: "SELECT * FROM ${users} u JOIN ${orders} o ON u.id = o.user_id",
+			test: models.Test{Project: "prod", Inputs: []models.Input{
+				{TableName: "users", Dataset: "crm"},
+				{TableName: "orders", Project: "sales-prod", Dataset: "sales"},
+			}},
+			expected: "SELECT * FROM `prod.crm.users` u JOIN `sales-prod.sales.orders` o ON u.id = o.user_id",
 		},
 		{
-			name:      "Different column count",
-			actual:    [][]string{{"1", "2", "3"}, {"4", "5", "6"}},
-			expected:  [][]string{{"1", "2"}, {"4", "5"}},
-			match:     false,
-			diffCount: 2,
+			name:     "Isolated dataset queried from another project",
+			query:    "SELECT * FROM ${users}",
+			test:     models.Test{Project: "prod", Inputs: []models.Input{{TableName: "users"}}},
+			expected: "SELECT * FROM `test-project.test_dataset_1.users`",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			match, differences := runner.CompareResults(tt.actual, tt.expected)
-			if match != tt.match {
-				t.Errorf("Expected match to be %v, got %v", tt.match, match)
+			refs := tableRefs(&tt.test, "test_dataset_1")
+			got, err := renderVars(tt.query, refs, nil)
+			if err != nil {
+				t.Fatalf("Failed to render tables: %v", err)
 			}
-			if len(differences) != tt.diffCount {
-				t.Errorf("Expected %d differences, got %d", tt.diffCount, len(differences))
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
 			}
 		})
 	}
 }
+
+func TestProjectsFor(t *testing.T) {
+	tests := []models.Test{
+		{Name: "default", Inputs: []models.Input{{TableName: "users"}}},
+		{Name: "prod", Project: "prod", Inputs: []models.Input{
+			{TableName: "users", Dataset: "crm"},
+			{TableName: "orders", Project: "sales-prod", Dataset: "sales"},
+		}},
+		{Name: "prod again", Inputs: []models.Input{{TableName: "users", Project: "prod", Dataset: "crm"}}},
+	}
+
+	expected := []Project{
+		{ID: "test-project"},
+		{ID: "prod", Datasets: []string{"crm"}},
+		{ID: "sales-prod", Datasets: []string{"sales"}},
+	}
+	if projects := ProjectsFor(tests); !reflect.DeepEqual(projects, expected) {
+		t.Errorf("Expected projects %v, got %v", expected, projects)
+	}
+}
+
+func TestDatasetFor(t *testing.T) {
+	runner := &TestRunner{}
+	first := &models.Test{Name: "first"}
+	second := &models.Test{Name: "second"}
+
+	if runner.datasetFor(first) == runner.datasetFor(second) {
+		t.Error("Expected tests to get distinct datasets")
+	}
+	if runner.datasetFor(first) != runner.datasetFor(first) {
+		t.Error("Expected a test to keep its dataset")
+	}
+
+	dataset := runner.datasetFor(first)
+	runner.releaseDataset(first)
+	if len(runner.datasets) != 1 {
+		t.Errorf("Expected a released dataset to be forgotten, got %v", runner.datasets)
+	}
+	if runner.datasetFor(first) == dataset {
+		t.Error("Expected a released test to get a new dataset")
+	}
+}
+
+func TestRenderQuery(t *testing.T) {
+	runner := &TestRunner{}
+	test := &models.Test{
+		Name:          "mapped",
+		Query:         "SELECT * FROM sales.orders JOIN ${users} USING (id)",
+		Inputs:        []models.Input{{TableName: "orders", File: "orders.csv"}, {TableName: "users", File: "users.csv"}},
+		TableMappings: map[string]string{"sales.orders": "orders"},
+	}
+	dataset := testDatasetID
+
+	query, err := runner.RenderQuery(test)
+	if err != nil {
+		t.Fatalf("Failed to render query: %v", err)
+	}
+	expected := fmt.Sprintf("SELECT * FROM `%s.orders` JOIN `%s.users` USING (id);", dataset, dataset)
+	if query != expected {
+		t.Errorf("Expected query %q, got %q", expected, query)
+	}
+
+	// Variables are rendered before production references are rewritten
+	test.Query = "SELECT * FROM `${project}.analytics.users`"
+	test.Vars = map[string]string{"project": "prod-project"}
+	test.TableMappings = map[string]string{"prod-project.analytics.users": "users"}
+	query, err = runner.RenderQuery(test)
+	if err != nil {
+		t.Fatalf("Failed to render query: %v", err)
+	}
+	expected = fmt.Sprintf("SELECT * FROM `%s.users`;", dataset)
+	if query != expected {
+		t.Errorf("Expected query %q, got %q", expected, query)
+	}
+
+	test.TableMappings = map[string]string{"sales.customers": "customers"}
+	if _, err := runner.RenderQuery(test); err == nil {
+		t.Error("Expected an error due to mapping to an unknown table, got none")
+	}
+
+	if len(runner.datasets) != 0 || runner.created != 0 {
+		t.Errorf("Expected rendering not to reserve a dataset, got %v", runner.datasets)
+	}
+}
+
+func TestRunTestIsolation(t *testing.T) {
+	runner, err := NewTestRunner()
+	if err != nil {
+		t.Fatalf("Failed to create TestRunner: %v", err)
+	}
+	defer runner.Close()
+
+	tmpDir, err := os.MkdirTemp("", "isolation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	queryFile := filepath.Join(tmpDir, "query.sql")
+	if err := os.WriteFile(queryFile, []byte("SELECT name FROM ${TABLE}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both tests load a table called users, with different contents
+	var tests []*models.Test
+	for _, name := range []string{"foo", "bar"} {
+		inputFile := filepath.Join(tmpDir, name+".csv")
+		if err := os.WriteFile(inputFile, []byte("name\n"+name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		tests = append(tests, &models.Test{
+			Name:      name,
+			QueryFile: queryFile,
+			InputFile: inputFile,
+			TableName: "users",
+		})
+	}
+
+	var wg sync.WaitGroup
+	for _, test := range tests {
+		wg.Add(1)
+		go func(test *models.Test) {
+			defer wg.Done()
+			results, err := runner.RunTest(test)
+			if err != nil {
+				t.Errorf("RunTest failed for '%s': %v", test.Name, err)
+				return
+			}
+			if len(results.Rows) != 1 || results.Rows[0][0] != test.Name {
+				t.Errorf("Expected only '%s' in results, got %v", test.Name, results.Rows)
+			}
+		}(test)
+	}
+	wg.Wait()
+
+	if len(runner.datasets) != 0 {
+		t.Errorf("Expected the datasets of finished tests to be released, got %v", runner.datasets)
+	}
+}
+
+func TestFixture(t *testing.T) {
+	runner, err := NewTestRunner()
+	if err != nil {
+		t.Fatalf("Failed to create TestRunner: %v", err)
+	}
+	defer runner.Close()
+
+	users := Table("users").
+		Column("id", INT64).
+		Column("name", STRING).
+		Column("score", FLOAT64).
+		Row(1, "foo", 1.5).
+		Row(2, "bar", nil)
+	f := runner.Fixture().Add(users)
+
+	passed, differences, err := f.Query("SELECT id, name, score FROM ${users}").
+		Columns("id", "name", "score").
+		Row(2, "bar", nil).
+		Row(1, "foo", 1.5).
+		Unordered().
+		Check()
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !passed {
+		t.Errorf("Expected fixture query to pass, got differences %v", differences)
+	}
+
+	passed, _, err = f.Query("SELECT COUNT(*) AS n FROM ${users}").Columns("n").Row(3).Check()
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if passed {
+		t.Error("Expected a wrong count to fail")
+	}
+}
+
+func TestRunTestDeclaredDataset(t *testing.T) {
+	test := &models.Test{
+		Name:    "Production names",
+		Query:   "SELECT u.name, o.amount FROM `prod.crm.users` u JOIN sales.orders o ON o.user_id = u.id",
+		Project: "prod",
+		Inputs: []models.Input{
+			{TableName: "users", Dataset: "crm", Rows: models.NewInlineTable([][]string{{"id", "name"}, {"1", "foo"}})},
+			{TableName: "orders", Dataset: "sales", Rows: models.NewInlineTable([][]string{{"user_id", "amount"}, {"1", "10"}})},
+		},
+	}
+
+	runner, err := NewTestRunner(ProjectsFor([]models.Test{*test})...)
+	if err != nil {
+		t.Fatalf("Failed to create TestRunner: %v", err)
+	}
+	defer runner.Close()
+
+	// Running twice checks that the tables are dropped from the shared datasets
+	for i := 0; i < 2; i++ {
+		results, err := runner.RunTest(test)
+		if err != nil {
+			t.Fatalf("RunTest failed: %v", err)
+		}
+		if len(results.Rows) != 1 || results.Rows[0][0] != "foo" || results.Rows[0][1] != "10" {
+			t.Errorf("Expected a single joined row, got %v", results.Rows)
+		}
+	}
+}
+
+func TestRunTestParams(t *testing.T) {
+	startDate := "2024-01-02"
+	test := &models.Test{
+		Name:  "Params",
+		Query: "SELECT id FROM ${orders} WHERE day >= @start_date AND id IN UNNEST(@ids) ORDER BY id",
+		Inputs: []models.Input{{
+			TableName:       "orders",
+			Rows:            models.NewInlineTable([][]string{{"id", "day"}, {"1", "2024-01-01"}, {"2", "2024-01-02"}, {"3", "2024-01-03"}}),
+			SchemaOverrides: map[string]string{"id": "INTEGER", "day": "DATE"},
+		}},
+		Params: []models.Param{
+			{Name: "start_date", Type: "DATE", Value: models.ParamValue{Scalar: &startDate}},
+			{Name: "ids", Type: "ARRAY<INT64>", Value: models.ParamValue{Array: []models.ParamValue{scalar("1"), scalar("3")}}},
+		},
+	}
+
+	runner, err := NewTestRunner()
+	if err != nil {
+		t.Fatalf("Failed to create TestRunner: %v", err)
+	}
+	defer runner.Close()
+
+	results, err := runner.RunTest(test)
+	if err != nil {
+		t.Fatalf("RunTest failed: %v", err)
+	}
+	expected := [][]string{{"id"}, {"3"}}
+	if table := results.Table(); !reflect.DeepEqual(table, expected) {
+		t.Errorf("Expected a single row with id 3, got %v", table)
+	}
+
+	// NULL parameters are typed, so they compare with typed columns
+	test.Query = "SELECT COUNT(*) AS count FROM ${orders} WHERE id = @id OR day = @day OR (@id IS NULL AND @day IS NULL)"
+	test.Params = []models.Param{{Name: "id", Type: "INT64"}, {Name: "day", Type: "DATE"}}
+	results, err = runner.RunTest(test)
+	if err != nil {
+		t.Fatalf("RunTest with NULL params failed: %v", err)
+	}
+	expected = [][]string{{"count"}, {"3"}}
+	if table := results.Table(); !reflect.DeepEqual(table, expected) {
+		t.Errorf("Expected every row to match NULL params, got %v", table)
+	}
+}
@@ -0,0 +1,144 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/JoseTorrado/bqtest/pkg/models"
+)
+
+// readSchemaFile reads a bq-style JSON schema file (name, type, mode, fields,
+// description), including REQUIRED/NULLABLE/REPEATED modes and nested records
+func readSchemaFile(filename string) (bigquery.Schema, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema file: %v", err)
+	}
+
+	schema, err := bigquery.SchemaFromJSON(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema file '%s': %v", filename, err)
+	}
+	if err := normalizeSchemaTypes(schema); err != nil {
+		return nil, fmt.Errorf("invalid schema file '%s': %v", filename, err)
+	}
+	return schema, nil
+}
+
+// normalizeSchemaTypes resolves standard SQL type aliases in nested fields too
+func normalizeSchemaTypes(schema bigquery.Schema) error {
+	for _, field := range schema {
+		fieldType, err := models.ParseFieldType(string(field.Type))
+		if err != nil {
+			return fmt.Errorf("field '%s': %v", field.Name, err)
+		}
+		field.Type = fieldType
+		if err := normalizeSchemaTypes(field.Schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// standardTypeNames are the standard SQL names of the field types the BigQuery
+// client names by their legacy names
+var standardTypeNames = map[bigquery.FieldType]bigquery.FieldType{
+	bigquery.IntegerFieldType: "INT64",
+	bigquery.FloatFieldType:   "FLOAT64",
+	bigquery.BooleanFieldType: "BOOL",
+}
+
+// SchemaJSON encodes a schema in the bq-style JSON schema file format, with
+// standard SQL type names such as INT64 rather than the legacy INTEGER
+func SchemaJSON(schema bigquery.Schema) ([]byte, error) {
+	return standardSQLSchema(schema).ToJSONFields()
+}
+
+// standardSQLSchema returns a copy of the schema using standard SQL type names
+func standardSQLSchema(schema bigquery.Schema) bigquery.Schema {
+	if schema == nil {
+		return nil
+	}
+	standard := make(bigquery.Schema, len(schema))
+	for i, field := range schema {
+		copied := *field
+		if name, ok := standardTypeNames[copied.Type]; ok {
+			copied.Type = name
+		}
+		copied.Schema = standardSQLSchema(field.Schema)
+		standard[i] = &copied
+	}
+	return standard
+}
+
+// checkCSVHeaders validates every CSV header as a column name and rejects
+// duplicates, which BigQuery compares case-insensitively
+func checkCSVHeaders(headers []string) error {
+	seen := make(map[string]int, len(headers))
+	for i, header := range headers {
+		if err := models.ValidateFieldName(header); err != nil {
+			return fmt.Errorf("invalid header in column %d: %v", i+1, err)
+		}
+		if j, ok := seen[strings.ToLower(header)]; ok {
+			return fmt.Errorf("duplicate header '%s' in columns %d and %d", header, j+1, i+1)
+		}
+		seen[strings.ToLower(header)] = i
+	}
+	return nil
+}
+
+// matchCSVHeaders maps every CSV header to the position of its field in the
+// schema. Headers and fields must match one to one, ignoring case and order.
+func matchCSVHeaders(headers []string, schema bigquery.Schema) ([]int, error) {
+	fields := make(map[string]int, len(schema))
+	for i, field := range schema {
+		if field.Type == bigquery.RecordFieldType || field.Repeated {
+			return nil, fmt.Errorf("field '%s': RECORD and REPEATED fields are not supported in CSV inputs", field.Name)
+		}
+		fields[strings.ToLower(field.Name)] = i
+	}
+
+	positions := make([]int, len(headers))
+	matched := make(map[int]bool, len(headers))
+	var unknown []string
+	for i, header := range headers {
+		j, ok := fields[strings.ToLower(header)]
+		if !ok {
+			unknown = append(unknown, header)
+			continue
+		}
+		positions[i] = j
+		matched[j] = true
+	}
+
+	var missing []string
+	for i, field := range schema {
+		if !matched[i] {
+			missing = append(missing, field.Name)
+		}
+	}
+
+	if len(unknown) > 0 || len(missing) > 0 {
+		return nil, fmt.Errorf("CSV headers don't match the schema: columns not in schema %v, schema fields missing from CSV %v", unknown, missing)
+	}
+	return positions, nil
+}
+
+// checkJSONFields fails on keys the schema doesn't define and on missing REQUIRED fields
+func checkJSONFields(record map[string]interface{}, schema bigquery.Schema) error {
+	fields := make(map[string]bool, len(schema))
+	for _, field := range schema {
+		fields[field.Name] = true
+		if field.Required && record[field.Name] == nil {
+			return fmt.Errorf("missing value for REQUIRED field '%s'", field.Name)
+		}
+	}
+	for name := range record {
+		if !fields[name] {
+			return fmt.Errorf("field '%s' is not in the schema", name)
+		}
+	}
+	return nil
+}
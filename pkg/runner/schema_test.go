@@ -0,0 +1,287 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/JoseTorrado/bqtest/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+const usersSchema = `[
+  {"name": "id", "type": "INTEGER", "mode": "REQUIRED"},
+  {"name": "name", "type": "STRING", "mode": "NULLABLE", "description": "Display name"},
+  {"name": "tags", "type": "STRING", "mode": "REPEATED"},
+  {"name": "address", "type": "RECORD", "fields": [
+    {"name": "city", "type": "STRING"}
+  ]}
+]`
+
+func TestReadSchemaFile(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "users.json")
+	if err := os.WriteFile(path, []byte(usersSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := readSchemaFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read schema file: %v", err)
+	}
+
+	expected := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType, Required: true},
+		{Name: "name", Type: bigquery.StringFieldType, Description: "Display name"},
+		{Name: "tags", Type: bigquery.StringFieldType, Repeated: true},
+		{Name: "address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+			{Name: "city", Type: bigquery.StringFieldType},
+		}},
+	}
+	if !reflect.DeepEqual(schema, expected) {
+		t.Errorf("Unexpected schema:\n got %v\nwant %v", schema, expected)
+	}
+}
+
+func TestReadCSVInputWithSchemaFile(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	schemaPath := filepath.Join(tmpDir, "users.json")
+	schemaJSON := `[{"name": "id", "type": "INTEGER", "mode": "REQUIRED"}, {"name": "name", "type": "STRING"}]`
+	if err := os.WriteFile(schemaPath, []byte(schemaJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Headers in a different order", func(t *testing.T) {
+		csvPath := filepath.Join(tmpDir, "users.csv")
+		if err := os.WriteFile(csvPath, []byte("name,id\nfoo,1"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		schema, rows, err := readCSVInput(&models.Input{File: csvPath, Schema: schemaPath}, "")
+		if err != nil {
+			t.Fatalf("Failed to read CSV input: %v", err)
+		}
+		if !schema[0].Required {
+			t.Error("Expected id to be REQUIRED")
+		}
+		if !reflect.DeepEqual(rows[0], []bigquery.Value{int64(1), "foo"}) {
+			t.Errorf("Expected values in schema order, got %v", rows[0])
+		}
+	})
+
+	t.Run("Headers don't match", func(t *testing.T) {
+		csvPath := filepath.Join(tmpDir, "mismatch.csv")
+		if err := os.WriteFile(csvPath, []byte("id,email\n1,foo@example.com"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, _, err := readCSVInput(&models.Input{File: csvPath, Schema: schemaPath}, ""); err == nil {
+			t.Error("Expected an error due to mismatched headers, got none")
+		}
+	})
+
+	t.Run("NULL in a REQUIRED column", func(t *testing.T) {
+		csvPath := filepath.Join(tmpDir, "required.csv")
+		if err := os.WriteFile(csvPath, []byte("id,name\n1,foo\n,bar"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, _, err := readCSVInput(&models.Input{File: csvPath, Schema: schemaPath}, "")
+		if err == nil || !strings.Contains(err.Error(), "row 2") || !strings.Contains(err.Error(), "'id'") {
+			t.Errorf("Expected an error naming row 2 and column id, got %v", err)
+		}
+	})
+}
+
+func TestCheckJSONFields(t *testing.T) {
+	schema := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType, Required: true},
+		{Name: "name", Type: bigquery.StringFieldType},
+	}
+
+	if err := checkJSONFields(map[string]interface{}{"id": 1}, schema); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := checkJSONFields(map[string]interface{}{"name": "foo"}, schema); err == nil {
+		t.Error("Expected an error due to missing REQUIRED field, got none")
+	}
+	if err := checkJSONFields(map[string]interface{}{"id": 1, "email": "foo@example.com"}, schema); err == nil {
+		t.Error("Expected an error due to unknown field, got none")
+	}
+}
+
+func TestReadCSVInputNulls(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "nulls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	csvPath := filepath.Join(tmpDir, "users.csv")
+	if err := os.WriteFile(csvPath, []byte("id,name,age\n1,,\\N\n\\N,\\N,30"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	input := &models.Input{File: csvPath, SchemaOverrides: map[string]string{"id": "INTEGER", "age": "INTEGER"}}
+
+	t.Run("Without marker", func(t *testing.T) {
+		if _, _, err := readCSVInput(input, ""); err == nil {
+			t.Error("Expected an error converting \\N to INTEGER, got none")
+		}
+	})
+
+	t.Run("With marker", func(t *testing.T) {
+		_, rows, err := readCSVInput(input, "\\N")
+		if err != nil {
+			t.Fatalf("Failed to read CSV input: %v", err)
+		}
+		expected := [][]bigquery.Value{
+			{int64(1), "", nil},
+			{nil, nil, int64(30)},
+		}
+		if !reflect.DeepEqual(rows, expected) {
+			t.Errorf("Expected rows %v, got %v", expected, rows)
+		}
+	})
+}
+
+func TestReadCSVInputInline(t *testing.T) {
+	input := &models.Input{
+		TableName:       "users",
+		Rows:            models.NewInlineTable([][]string{{"id", "name"}, {"1", "Alice"}, {"", "Bob"}}),
+		SchemaOverrides: map[string]string{"id": "INTEGER"},
+	}
+
+	schema, rows, err := readCSVInput(input, "")
+	if err != nil {
+		t.Fatalf("Failed to read inline input: %v", err)
+	}
+	if len(schema) != 2 || schema[0].Type != bigquery.IntegerFieldType {
+		t.Errorf("Expected id to be an INTEGER column, got %v", schema)
+	}
+	expected := [][]bigquery.Value{
+		{int64(1), "Alice"},
+		{nil, "Bob"},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected rows %v, got %v", expected, rows)
+	}
+}
+
+func TestReadCSVInputInlineNull(t *testing.T) {
+	var input models.Input
+	content := "table_name: users\nrows:\n  - {id: 1, name: null}\n  - {id: 2, name: \"\"}\n"
+	if err := yaml.Unmarshal([]byte(content), &input); err != nil {
+		t.Fatalf("Failed to unmarshal input: %v", err)
+	}
+
+	_, rows, err := readCSVInput(&input, "")
+	if err != nil {
+		t.Fatalf("Failed to read inline input: %v", err)
+	}
+	expected := [][]bigquery.Value{
+		{"1", nil},
+		{"2", ""},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected rows %v, got %v", expected, rows)
+	}
+}
+
+func TestReadCSVInputInferSchema(t *testing.T) {
+	input := &models.Input{
+		TableName:       "users",
+		Rows:            models.NewInlineTable([][]string{{"id", "zip", "age"}, {"1", "02134", "30"}, {"2", "10001", ""}}),
+		SchemaOverrides: map[string]string{"zip": "STRING"},
+		InferSchema:     true,
+	}
+
+	schema, rows, err := readCSVInput(input, "")
+	if err != nil {
+		t.Fatalf("Failed to read input: %v", err)
+	}
+
+	expectedTypes := []bigquery.FieldType{bigquery.IntegerFieldType, bigquery.StringFieldType, bigquery.IntegerFieldType}
+	for i, fieldType := range expectedTypes {
+		if schema[i].Type != fieldType {
+			t.Errorf("Expected field %d to be %s, got %s", i, fieldType, schema[i].Type)
+		}
+	}
+	expected := [][]bigquery.Value{
+		{int64(1), "02134", int64(30)},
+		{int64(2), "10001", nil},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected rows %v, got %v", expected, rows)
+	}
+}
+
+func TestReadCSVInputInferSchemaInlineNull(t *testing.T) {
+	var input models.Input
+	content := "table_name: users\ninfer_schema: true\nrows:\n  - {id: 1}\n  - {id: null}\n"
+	if err := yaml.Unmarshal([]byte(content), &input); err != nil {
+		t.Fatalf("Failed to unmarshal input: %v", err)
+	}
+
+	for _, marker := range []string{"", `\N`} {
+		schema, rows, err := readCSVInput(&input, marker)
+		if err != nil {
+			t.Fatalf("Failed to read input with marker %q: %v", marker, err)
+		}
+		if schema[0].Type != bigquery.IntegerFieldType {
+			t.Errorf("Expected id to be inferred as INTEGER with marker %q, got %s", marker, schema[0].Type)
+		}
+		expected := [][]bigquery.Value{{int64(1)}, {nil}}
+		if !reflect.DeepEqual(rows, expected) {
+			t.Errorf("Expected rows %v with marker %q, got %v", expected, marker, rows)
+		}
+	}
+}
+
+func TestReadCSVInputHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		wantErr bool
+	}{
+		{"Preserves case", []string{"userId", "country"}, false},
+		{"Empty header", []string{"id", ""}, true},
+		{"Invalid header", []string{"id", "first name"}, true},
+		{"Duplicate header", []string{"id", "ID"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := make([]string, len(tt.headers))
+			input := &models.Input{TableName: "users", Rows: models.NewInlineTable([][]string{tt.headers, row})}
+
+			schema, _, err := readCSVInput(input, "")
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to read input: %v", err)
+			}
+			for i, field := range schema {
+				if field.Name != tt.headers[i] {
+					t.Errorf("Expected column name %q, got %q", tt.headers[i], field.Name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+)
+
+// Standard SQL names of the BigQuery field types, for declaring fixture columns
+const (
+	STRING     = bigquery.StringFieldType
+	BYTES      = bigquery.BytesFieldType
+	INT64      = bigquery.IntegerFieldType
+	FLOAT64    = bigquery.FloatFieldType
+	NUMERIC    = bigquery.NumericFieldType
+	BIGNUMERIC = bigquery.BigNumericFieldType
+	BOOL       = bigquery.BooleanFieldType
+	TIMESTAMP  = bigquery.TimestampFieldType
+	DATE       = bigquery.DateFieldType
+	TIME       = bigquery.TimeFieldType
+	DATETIME   = bigquery.DateTimeFieldType
+	GEOGRAPHY  = bigquery.GeographyFieldType
+	JSON       = bigquery.JSONFieldType
+	INTERVAL   = bigquery.IntervalFieldType
+)
+
+// parseDecimal parses an exact decimal such as 1.50 or 2.5e3. Unlike
+// big.Rat.SetString, it rejects fractions such as 1/3.
+func parseDecimal(value string) (*big.Rat, bool) {
+	if strings.Contains(value, "/") {
+		return nil, false
+	}
+	return new(big.Rat).SetString(value)
+}
+
+// convertValue parses the text form of a value into the Go type the BigQuery
+// client expects for fieldType. BYTES are base64 encoded, GEOGRAPHY values are
+// WKT strings and INTERVAL values use the canonical Y-M D H:M:S format.
+func convertValue(value string, fieldType bigquery.FieldType) (bigquery.Value, error) {
+	switch fieldType {
+	case bigquery.IntegerFieldType:
+		return strconv.ParseInt(value, 10, 64)
+	case bigquery.FloatFieldType:
+		return strconv.ParseFloat(value, 64)
+	case bigquery.NumericFieldType, bigquery.BigNumericFieldType:
+		r, ok := parseDecimal(value)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s value '%s'", fieldType, value)
+		}
+		return r, nil
+	case bigquery.BooleanFieldType:
+		return strconv.ParseBool(value)
+	case bigquery.TimestampFieldType:
+		return parseTimestamp(value)
+	case bigquery.DateFieldType:
+		return civil.ParseDate(value)
+	case bigquery.TimeFieldType:
+		return civil.ParseTime(value)
+	case bigquery.DateTimeFieldType:
+		return civil.ParseDateTime(strings.Replace(value, " ", "T", 1))
+	case bigquery.BytesFieldType:
+		return base64.StdEncoding.DecodeString(value)
+	case bigquery.JSONFieldType:
+		if !json.Valid([]byte(value)) {
+			return nil, fmt.Errorf("invalid JSON value '%s'", value)
+		}
+		return value, nil
+	case bigquery.IntervalFieldType:
+		return bigquery.ParseInterval(value)
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+)
+
+func TestConvertValue(t *testing.T) {
+	tests := []struct {
+		value     string
+		fieldType bigquery.FieldType
+		expected  bigquery.Value
+	}{
+		{"42", bigquery.IntegerFieldType, int64(42)},
+		{"1.5", bigquery.FloatFieldType, 1.5},
+		{"12.345", bigquery.NumericFieldType, big.NewRat(12345, 1000)},
+		{"true", bigquery.BooleanFieldType, true},
+		{"2024-01-01T12:00:00Z", bigquery.TimestampFieldType, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{"2024-01-01", bigquery.DateFieldType, civil.Date{Year: 2024, Month: 1, Day: 1}},
+		{"12:30:00", bigquery.TimeFieldType, civil.Time{Hour: 12, Minute: 30}},
+		{"2024-01-01 12:30:00", bigquery.DateTimeFieldType, civil.DateTime{Date: civil.Date{Year: 2024, Month: 1, Day: 1}, Time: civil.Time{Hour: 12, Minute: 30}}},
+		{"aGVsbG8=", bigquery.BytesFieldType, []byte("hello")},
+		{"POINT(1 2)", bigquery.GeographyFieldType, "POINT(1 2)"},
+		{`{"a": 1}`, bigquery.JSONFieldType, `{"a": 1}`},
+		{"1-2 3 4:5:6", bigquery.IntervalFieldType, &bigquery.IntervalValue{Years: 1, Months: 2, Days: 3, Hours: 4, Minutes: 5, Seconds: 6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.fieldType), func(t *testing.T) {
+			value, err := convertValue(tt.value, tt.fieldType)
+			if err != nil {
+				t.Fatalf("Failed to convert %q: %v", tt.value, err)
+			}
+			if r, ok := value.(*big.Rat); ok {
+				if r.Cmp(tt.expected.(*big.Rat)) != 0 {
+					t.Errorf("Expected %v, got %v", tt.expected, value)
+				}
+				return
+			}
+			if !reflect.DeepEqual(value, tt.expected) {
+				t.Errorf("Expected %#v, got %#v", tt.expected, value)
+			}
+		})
+	}
+
+	for _, tt := range []struct {
+		value     string
+		fieldType bigquery.FieldType
+	}{
+		{"abc", bigquery.NumericFieldType},
+		{"1/3", bigquery.NumericFieldType},
+		{"not base64!", bigquery.BytesFieldType},
+		{"{", bigquery.JSONFieldType},
+		{"2024-01-01 02:00:00 PST", bigquery.TimestampFieldType},
+	} {
+		if _, err := convertValue(tt.value, tt.fieldType); err == nil {
+			t.Errorf("Expected an error converting %q to %s, got none", tt.value, tt.fieldType)
+		}
+	}
+}
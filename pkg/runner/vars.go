@@ -0,0 +1,72 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/JoseTorrado/bqtest/pkg/models"
+)
+
+// renderVars replaces every ${name} in the query with the reference of the
+// input table or the value of the variable of that name, including inside
+// string literals so that '${start_date}' can be written. ${TABLE} stands for
+// the table of single-table queries. Referencing an undefined name is an
+// error; $${ is kept as a literal ${.
+func renderVars(query string, tables, vars map[string]string) (string, error) {
+	var out strings.Builder
+	undefined := make(map[string]bool)
+	for i := 0; i < len(query); {
+		if strings.HasPrefix(query[i:], "$${") {
+			out.WriteString("${")
+			i += 3
+			continue
+		}
+		if !strings.HasPrefix(query[i:], "${") {
+			out.WriteByte(query[i])
+			i++
+			continue
+		}
+
+		end := strings.IndexByte(query[i:], '}')
+		if end < 0 {
+			out.WriteString("${")
+			i += 2
+			continue
+		}
+		name := query[i+2 : i+end]
+		if ref, ok := tableRef(tables, name); ok {
+			out.WriteString(ref)
+		} else if !models.IsIdentifier(name) {
+			out.WriteString("${")
+			i += 2
+			continue
+		} else if value, ok := vars[name]; ok {
+			out.WriteString(value)
+		} else {
+			undefined[name] = true
+		}
+		i += end + 1
+	}
+
+	if len(undefined) > 0 {
+		names := make([]string, 0, len(undefined))
+		for name := range undefined {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("undefined query variables or tables: %s (write $${ for a literal ${)", strings.Join(names, ", "))
+	}
+	return out.String(), nil
+}
+
+// tableRef returns the reference of the input table a placeholder names
+func tableRef(tables map[string]string, name string) (string, bool) {
+	if name == "TABLE" && len(tables) == 1 {
+		for _, ref := range tables {
+			return ref, true
+		}
+	}
+	ref, ok := tables[name]
+	return ref, ok
+}
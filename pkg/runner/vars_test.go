@@ -0,0 +1,64 @@
+package runner
+
+import "testing"
+
+func TestRenderVars(t *testing.T) {
+	tables := map[string]string{"users": "`test_dataset.users`", "user-events": "`test_dataset.user-events`"}
+	vars := map[string]string{"start_date": "2024-01-01", "threshold": "10"}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "Variables",
+			query:    "SELECT * FROM t WHERE d >= '${start_date}' AND n > ${threshold}",
+			expected: "SELECT * FROM t WHERE d >= '2024-01-01' AND n > 10",
+		},
+		{
+			name:     "Escaped placeholder",
+			query:    "SELECT '$${start_date}', '${not a var}', '$5'",
+			expected: "SELECT '${start_date}', '${not a var}', '$5'",
+		},
+		{
+			name:     "Tables and variables",
+			query:    "SELECT * FROM ${users} JOIN ${user-events} USING (id) WHERE d >= '${start_date}'",
+			expected: "SELECT * FROM `test_dataset.users` JOIN `test_dataset.user-events` USING (id) WHERE d >= '2024-01-01'",
+		},
+		{
+			name:     "Escaped table placeholder",
+			query:    "SELECT '$${users}' FROM ${users}",
+			expected: "SELECT '${users}' FROM `test_dataset.users`",
+		},
+		{
+			name:    "TABLE placeholder with multiple tables",
+			query:   "SELECT * FROM ${TABLE}",
+			wantErr: true,
+		},
+		{
+			name:    "Undefined variable",
+			query:   "SELECT ${end_date}",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderVars(tt.query, tables, vars)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to render variables: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
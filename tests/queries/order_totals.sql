@@ -0,0 +1,5 @@
+SELECT u.country, COUNT(o.order_id) AS order_count
+FROM ${users} u
+JOIN ${orders} o ON o.user_id = u.id
+GROUP BY u.country
+ORDER BY u.country